@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// command is a subcommand of git-cm, run as `git cm <name>`.
+type command struct {
+	name    string
+	args    string // arguments shown after the name in the usage line
+	summary string
+	hidden  bool // not listed by the help, for the commands run by scripts
+	run     func(args []string) int
+	// flags returns the flag set of the command, used to complete the flag names;
+	// it is nil for a command without flags.
+	flags func() *flag.FlagSet
+}
+
+// commands returns the subcommands in the order they are listed by the help.
+func commands() []command {
+	return []command{
+		{
+			name: "commit", args: "[flags]",
+			summary: "Compose the commit message in the TUI, or from flags with --yes, and commit",
+			run:     runCommit,
+			flags:   func() *flag.FlagSet { return newCommitFlagSet(&cliOptions{}) },
+		},
+		{
+			name: "lint", args: "--file FILE | --range RANGE [--format FORMAT]",
+			summary: "Lint a commit message file or the commits of a range",
+			run:     runLint,
+			flags:   func() *flag.FlagSet { return newLintFlagSet(&lintOptions{}) },
+		},
+		{
+			name: "hook", args: "install|uninstall [" + strings.Join(managedHooks, "|") + "]",
+			summary: "Install or uninstall the git hooks",
+			run:     runHookInstaller,
+		},
+		{
+			name: "config", args: "[--files] [--format FORMAT]",
+			summary: "Print the effective git-cm configuration",
+			run:     runConfig,
+			flags:   func() *flag.FlagSet { return newConfigFlagSet(&configOptions{}) },
+		},
+		{
+			name: "log", args: "[-n N]",
+			summary: "List the saved messages, numbered like --reuse=N",
+			run:     runLog,
+			flags:   func() *flag.FlagSet { return newLogFlagSet(new(int)) },
+		},
+		{
+			name: "completion", args: strings.Join(completionShells, "|"),
+			summary: "Print the shell completion script",
+			run:     runCompletion,
+		},
+		{name: "version", summary: "Print the version", run: runVersion},
+		{name: "help", args: "[COMMAND]", summary: "Show the help of git-cm or of a command", run: runHelp},
+		{
+			name: completeCommand, args: "[ARG...] CURRENT", hidden: true,
+			summary: "Print the completion candidates of the last argument, used by the completion scripts",
+			run:     runComplete,
+		},
+	}
+}
+
+// findCommand returns the named subcommand, or nil when there is none.
+func findCommand(name string) *command {
+	cmds := commands()
+	if i := slices.IndexFunc(cmds, func(c command) bool { return c.name == name }); i >= 0 {
+		return &cmds[i]
+	}
+	return nil
+}
+
+// isHelpFlag reports whether arg asks for help, as accepted by the flag package.
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "--h" || arg == "-help" || arg == "--help"
+}
+
+// run dispatches the command-line arguments (without the program name) to a subcommand and
+// returns the exit status. The leading -C options are applied first. Without a subcommand,
+// e.g. a bare `git cm` or `git cm --yes`, the arguments are the flags of the commit command.
+func run(args []string) int {
+	args, err := chdirOptions(args)
+	if err != nil {
+		return exitWithError(err)
+	}
+
+	switch {
+	case len(args) == 0:
+		return runCommit(args)
+	case isHelpFlag(args[0]):
+		printUsage(os.Stdout)
+		return exitOK
+	case strings.HasPrefix(args[0], "-"):
+		return runCommit(args)
+	}
+
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitError
+	}
+	return cmd.run(args[1:])
+}
+
+// printUsage writes the help of git-cm: its usage and the list of subcommands.
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: git cm [-C DIR] [COMMAND] [flags]\n\n")
+	fmt.Fprintf(w, "Compose Conventional Commits messages. Without a command, git cm runs commit.\n\n")
+	fmt.Fprintf(w, "Commands:\n")
+	cmds := slices.DeleteFunc(commands(), func(c command) bool { return c.hidden })
+	width := 0
+	for _, c := range cmds {
+		width = max(width, len(c.name))
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nOptions:\n")
+	fmt.Fprintf(w, "  -C DIR  Run as if git cm was started in DIR\n")
+	fmt.Fprintf(w, "\nRun 'git cm help COMMAND' or 'git cm COMMAND -h' for the flags of a command.\n")
+}
+
+// newFlagSet returns the flag set of the named subcommand, whose usage shows the command's
+// arguments, summary and flags.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("git-cm "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		cmd := findCommand(name)
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: git cm %s", name)
+		if cmd.args != "" {
+			fmt.Fprintf(w, " %s", cmd.args)
+		}
+		fmt.Fprintf(w, "\n\n%s.\n", cmd.summary)
+
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintf(w, "\nFlags:\n")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses the arguments of a subcommand. It returns false, with the exit status,
+// when the command must not run: the help was asked, or the flags are invalid, in which case
+// the error has already been reported with the usage.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitError, false
+	}
+	return exitOK, true
+}
+
+// runCommit implements `git cm commit`, also run by a bare `git cm`.
+func runCommit(args []string) int {
+	opts, err := parseOptions(args)
+	if err != nil {
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			return exitError
+		}
+		return exitWithError(err)
+	}
+
+	if opts.Version {
+		return runVersion(nil)
+	}
+	return doCommit(opts)
+}
+
+// runVersion implements `git cm version`.
+func runVersion(args []string) int {
+	fs := newFlagSet("version")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return exitWithError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+
+	fmt.Println(version)
+	return exitOK
+}
+
+// runHelp implements `git cm help [COMMAND]`.
+func runHelp(args []string) int {
+	fs := newFlagSet("help")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	switch fs.NArg() {
+	case 0:
+		printUsage(os.Stdout)
+		return exitOK
+	case 1:
+		cmd := findCommand(fs.Arg(0))
+		if cmd == nil {
+			return exitWithError(fmt.Errorf("unknown command %q", fs.Arg(0)))
+		}
+		return cmd.run([]string{"-h"})
+	default:
+		return exitWithError(fmt.Errorf("unexpected argument: %s", fs.Arg(1)))
+	}
+}
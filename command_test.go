@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	for _, name := range []string{"commit", "lint", "hook", "config", "log", "completion", "version", "help", completeCommand} {
+		if cmd := findCommand(name); cmd == nil || cmd.name != name {
+			t.Errorf("expected the %s command, got %v", name, cmd)
+		}
+	}
+	if cmd := findCommand("unknown"); cmd != nil {
+		t.Errorf("expected no command, got %v", cmd.name)
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{name: "Help", args: []string{"--help"}, expected: exitOK},
+		{name: "HelpCommand", args: []string{"help"}, expected: exitOK},
+		{name: "HelpOfCommand", args: []string{"help", "lint"}, expected: exitOK},
+		{name: "HelpOfUnknownCommand", args: []string{"help", "unknown"}, expected: exitError},
+		{name: "CommandHelp", args: []string{"config", "-h"}, expected: exitOK},
+		{name: "Version", args: []string{"version"}, expected: exitOK},
+		{name: "VersionFlag", args: []string{"--version"}, expected: exitOK},
+		{name: "UnknownCommand", args: []string{"unknown"}, expected: exitError},
+		{name: "UnexpectedArgument", args: []string{"version", "extra"}, expected: exitError},
+		{name: "InvalidCommitFlags", args: []string{"--amend", "--reuse"}, expected: exitError},
+		{name: "UnknownFlag", args: []string{"log", "--unknown"}, expected: exitError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := run(tt.args); code != tt.expected {
+				t.Errorf("expected exit code %d, got %d", tt.expected, code)
+			}
+		})
+	}
+}
@@ -3,42 +3,309 @@ package main
 import (
 	"errors"
 	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// doCommit executes the commit process by obtaining the repository info,
-// running the TUI via runTUI, constructing the commit message, and performing the commit.
+// authorHistoryLimit is the number of recent commits scanned for Co-authored-by suggestions.
+const authorHistoryLimit = 500
+
+// doCommit executes the commit process by obtaining the repository info and git-cm config,
+// building the commit message from the command-line options or by running the TUI via runTUI,
+// validating it, and performing the commit.
 // This function returns an int status code (0 on success, or an error code if an error occurs),
 // but the status code handling is left to the caller.
-func doCommit() int {
-	root, err := findRepoRoot()
+func doCommit(opts *cliOptions) int {
+	loc, err := findRepo()
 	if err != nil {
 		return exitWithError(err)
 	}
+	root := loc.Root
 
-	repo, err := openRepo(root)
+	cfg, err := loadSettings(root)
 	if err != nil {
 		return exitWithError(err)
 	}
 
-	author, err := getAuthorInfo(repo)
+	repo, err := openRepo(loc)
 	if err != nil {
 		return exitWithError(err)
 	}
 
-	msg, err := runTUI()
-	if err != nil {
-		if errors.Is(err, errQuit) {
-			fmt.Println("Quit selected")
-			return 0
+	// Past authors complete --author patterns and Co-authored-by trailers in the TUI.
+	var authors []string
+	if !opts.Yes || opts.Author != "" {
+		if authors, err = collectAuthors(repo, authorHistoryLimit); err != nil {
+			return exitWithError(err)
+		}
+	}
+
+	var amended *object.Commit
+	var base *commitMessage
+	if opts.Amend {
+		if amended, base, err = amendedMessage(repo, opts); err != nil {
+			return exitWithError(err)
 		}
+	} else if opts.Reuse > 0 {
+		if base, err = reusedMessage(repo, opts.Reuse); err != nil {
+			return exitWithError(err)
+		}
+	}
+
+	author, commitOpts, err := resolveCommitOptions(repo, opts, authors, amended)
+	if err != nil {
 		return exitWithError(err)
 	}
 
-	hash, err := commitRepo(repo, *author, msg)
+	msg, err := opts.message(base, cfg.DefaultPrefix)
 	if err != nil {
 		return exitWithError(err)
 	}
 
-	fmt.Printf("Commit created: %s\n", hash)
-	return 0
+	// The ticket ID of the branch goes into new messages; the TUI shows it in an editable field.
+	// It is inserted only in the message committed: the history and the draft keep the message
+	// as typed, so that a restored message does not carry the ticket twice.
+	var ticket string
+	if !opts.Amend {
+		if ticket, err = branchTicket(repo, cfg.Ticket); err != nil {
+			return exitWithError(err)
+		}
+	}
+
+	// The message being written is kept as a draft of the branch, except when amending.
+	var drafts *draftStore
+	if !opts.Amend {
+		if drafts, err = newDraftStore(repo); err != nil {
+			return exitWithError(err)
+		}
+	}
+
+	var tuiOpts tuiOptions
+	if !opts.Yes {
+		tuiOpts = tuiOptions{
+			Author:    author,
+			Committer: *commitOpts.Committer,
+			Authors:   authors,
+			Amend:     opts.Amend,
+			Drafts:    drafts,
+			Ticket:    ticket,
+		}
+		if opts.hasMessage() || base != nil {
+			tuiOpts.Message = msg
+		} else if opts.MessageFile != "" {
+			tuiOpts.Message = readMessageFile(opts.MessageFile)
+		}
+		// With --message-file, git has already built the tree, possibly from the temporary index
+		// of `git commit -a` or `git commit <paths>` that go-git does not read: no staging panel.
+		if err := loadTUIOptions(repo, root, cfg, opts.MessageFile == "", &tuiOpts); err != nil {
+			return exitWithError(err)
+		}
+	}
+
+	for {
+		if !opts.Yes {
+			res, err := runTUI(cfg, tuiOpts)
+			msg, ticket, author = res.Message, res.Ticket, res.Author
+			if err != nil {
+				if errors.Is(err, errQuit) {
+					// Keep what was typed, so that it can be recalled from the history.
+					recordMessage(repo, msg, "")
+					keepQuitDraft(drafts, res)
+					fmt.Println("Quit selected")
+					return exitOK
+				}
+				return exitWithError(err)
+			}
+		}
+
+		final := withTicket(msg, ticket, cfg.Ticket)
+		if err := final.validate(cfg); err != nil {
+			keepDraft(drafts, msg)
+			return exitWithError(err)
+		}
+		if opts.Yes {
+			// The TUI shows warnings inline; without it, print them to stderr.
+			for _, v := range lintMessage(final, cfg) {
+				if v.Severity == severityWarning {
+					fmt.Fprintf(os.Stderr, "Warning: %s\n", v.Message)
+				}
+			}
+		}
+
+		if opts.MessageFile != "" {
+			if err := writeMessageFile(opts.MessageFile, final); err != nil {
+				keepDraft(drafts, msg)
+				return exitWithError(err)
+			}
+			keepDraft(drafts, nil)
+			return exitOK
+		}
+
+		hash, err := commitRepo(repo, author, final, commitOpts)
+		recordMessage(repo, msg, hash)
+		if err != nil {
+			keepDraft(drafts, msg)
+			if !opts.Yes {
+				// Back to the TUI with the message kept, so that it can be fixed and committed again.
+				tuiOpts.retry(msg, ticket, author, err)
+				if tuiOpts.History, err = loadHistory(repo); err != nil {
+					return exitWithError(err)
+				}
+				continue
+			}
+
+			var he *hookError
+			if errors.As(err, &he) {
+				fmt.Fprint(os.Stderr, he.Output)
+			}
+			return exitWithError(err)
+		}
+
+		keepDraft(drafts, nil)
+		fmt.Printf("Commit created: %s\n", hash)
+		return exitOK
+	}
+}
+
+// loadTUIOptions loads what the TUI shows besides the message: the staging area when staging is
+// true, the message history and the templates. Without a message to edit, the draft left by a
+// previous session is offered for restoring.
+func loadTUIOptions(repo *git.Repository, root string, cfg *settings, staging bool, o *tuiOptions) error {
+	var err error
+	if staging {
+		if o.Staging, err = newStagingArea(repo); err != nil {
+			return err
+		}
+	}
+
+	if o.History, err = loadHistory(repo); err != nil {
+		return err
+	}
+
+	vars, err := newTemplateVars(repo, o.Ticket, o.Author)
+	if err != nil {
+		return err
+	}
+	if o.Templates, err = loadTemplates(repo, root, cfg, vars); err != nil {
+		return err
+	}
+	o.Template = defaultTemplate(cfg, o.Templates)
+
+	if o.Message == nil && o.Drafts != nil {
+		if o.Draft, err = o.Drafts.load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// resolveCommitOptions returns the author of the commit and the options of commitRepo: the author
+// and committer identities and dates from the git config, the environment and the --author and
+// --date flags (authors lists past authors for --author patterns), and the signer when the commit
+// is to be signed. When amending, the author of the amended commit is kept unless --reset-author is given.
+func resolveCommitOptions(repo *git.Repository, opts *cliOptions, authors []string, amended *object.Commit) (author, commitOptions, error) {
+	a, err := getAuthorInfo(repo)
+	if err != nil {
+		return author{}, commitOptions{}, err
+	}
+
+	committer, err := getCommitterInfo(repo)
+	if err != nil {
+		return author{}, commitOptions{}, err
+	}
+
+	commitOpts := commitOptions{NoVerify: opts.NoVerify, Committer: committer, Amend: amended != nil}
+	if amended != nil && !opts.ResetAuthor {
+		*a = author{Name: amended.Author.Name, Email: amended.Author.Email}
+		commitOpts.AuthorDate = amended.Author.When
+	} else if commitOpts.AuthorDate, err = envDate("GIT_AUTHOR_DATE"); err != nil {
+		return author{}, commitOptions{}, err
+	}
+
+	if opts.Author != "" {
+		if *a, err = findAuthor(opts.Author, authors); err != nil {
+			return author{}, commitOptions{}, err
+		}
+	}
+	if opts.Date != "" {
+		if commitOpts.AuthorDate, err = parseGitDate(opts.Date); err != nil {
+			return author{}, commitOptions{}, err
+		}
+	}
+	if commitOpts.CommitterDate, err = envDate("GIT_COMMITTER_DATE"); err != nil {
+		return author{}, commitOptions{}, err
+	}
+
+	sign := opts.Sign
+	if !sign && !opts.NoSign {
+		if sign, err = gpgSignEnabled(repo); err != nil {
+			return author{}, commitOptions{}, err
+		}
+	}
+	if sign {
+		if commitOpts.Signer, err = newCommitSigner(repo, *committer); err != nil {
+			return author{}, commitOptions{}, err
+		}
+	}
+	return *a, commitOpts, nil
+}
+
+// amendedMessage returns the HEAD commit and its message, parsed as the base of the amended message.
+// A message that is not a Conventional Commits message can only be amended when it is rewritten,
+// in the TUI or with the message flags.
+func amendedMessage(repo *git.Repository, opts *cliOptions) (*object.Commit, *commitMessage, error) {
+	head, err := resolveCommit(repo, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("nothing to amend: %w", err)
+	}
+
+	base, err := parseMessage(head.Message)
+	if err != nil && opts.Yes && !opts.hasMessage() {
+		return nil, nil, fmt.Errorf("%w: the message of HEAD cannot be kept: %w", errInvalidMessage, err)
+	}
+	return head, base, nil
+}
+
+// readMessageFile returns the Conventional Commits message already present in the message file
+// git passes to the prepare-commit-msg hook (e.g. from commit.template), or nil if there is none.
+func readMessageFile(path string) *commitMessage {
+	data, err := os.ReadFile(path)
+	if err != nil || cleanupMessage(string(data)) == "" {
+		return nil
+	}
+
+	msg, err := parseMessage(string(data))
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// writeMessageFile replaces the message in the file git passes to the prepare-commit-msg hook,
+// keeping the comment lines git wrote there so that they still show up in the editor.
+func writeMessageFile(path string, msg *commitMessage) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read message file: %w", err)
+	}
+
+	var comments []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "#") {
+			comments = append(comments, line)
+		}
+	}
+
+	content := msg.String() + "\n"
+	if len(comments) > 0 {
+		content += "\n" + strings.Join(comments, "\n") + "\n"
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write message file: %w", err)
+	}
+	return nil
 }
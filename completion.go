@@ -0,0 +1,330 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// completeCommand is the hidden command run by the completion scripts to get the candidates.
+const completeCommand = "__complete"
+
+// completionShells lists the shells `git cm completion` has a script for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// candidate is a completion candidate with an optional description.
+type candidate struct {
+	Value       string
+	Description string
+}
+
+// String returns the candidate as printed by __complete: the value, then a tab and the
+// description when there is one.
+func (c candidate) String() string {
+	if c.Description == "" {
+		return c.Value
+	}
+	return c.Value + "\t" + c.Description
+}
+
+// runCompletion implements `git cm completion bash|zsh|fish`, which prints the completion script
+// of the shell. The scripts complete both `git cm` and `git-cm` by running `git-cm __complete`.
+func runCompletion(args []string) int {
+	fs := newFlagSet("completion")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+
+	var script string
+	switch fs.Arg(0) {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return exitWithError(fmt.Errorf("unsupported shell %q", fs.Arg(0)))
+	}
+	fmt.Print(script)
+	return exitOK
+}
+
+// runComplete implements the hidden `git cm __complete ARG... CURRENT`, which prints the
+// candidates for CURRENT, one per line. The arguments are not parsed as flags.
+func runComplete(args []string) int {
+	for _, c := range complete(args) {
+		fmt.Println(c)
+	}
+	return exitOK
+}
+
+// complete returns the candidates for the last of the words, which follow `git cm` on the
+// command line: the word being completed, possibly empty. The leading -C options are applied
+// so that the values come from the config of that repository. No candidates lets the shell
+// complete file names, e.g. for -C or --file.
+func complete(words []string) []candidate {
+	if len(words) == 0 {
+		return nil
+	}
+	cur := words[len(words)-1]
+
+	args, err := chdirOptions(words[:len(words)-1])
+	if err != nil {
+		// Typically the directory of -C being completed.
+		return nil
+	}
+
+	name := "commit"
+	switch {
+	case len(args) > 0 && !strings.HasPrefix(args[0], "-"):
+		name, args = args[0], args[1:]
+	case len(args) == 0 && !strings.HasPrefix(cur, "-"):
+		return filterCandidates(commandCandidates(), cur)
+	}
+	cmd := findCommand(name)
+	if cmd == nil || cmd.hidden {
+		return nil
+	}
+
+	var fs *flag.FlagSet
+	if cmd.flags != nil {
+		fs = cmd.flags()
+	}
+	if len(args) > 0 {
+		if f := lookupFlag(fs, args[len(args)-1]); f != nil && !isBoolFlag(f) {
+			return filterCandidates(flagValues(name, f.Name), cur)
+		}
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		candidates := flagCandidates(fs)
+		if len(words) == 1 {
+			candidates = append(candidates, candidate{"-C", "Run as if git cm was started in DIR"})
+		}
+		return filterCandidates(candidates, cur)
+	}
+	return filterCandidates(argValues(name, countArgs(fs, args)), cur)
+}
+
+// filterCandidates returns the candidates starting with the word being completed.
+func filterCandidates(candidates []candidate, cur string) []candidate {
+	var filtered []candidate
+	for _, c := range candidates {
+		if strings.HasPrefix(c.Value, cur) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// commandCandidates returns the commands listed by the help.
+func commandCandidates() []candidate {
+	var candidates []candidate
+	for _, c := range commands() {
+		if !c.hidden {
+			candidates = append(candidates, candidate{c.name, c.summary})
+		}
+	}
+	return candidates
+}
+
+// flagCandidates returns the flags of the flag set: "-x" for one-letter flags and "--name"
+// for the others, described by their usage.
+func flagCandidates(fs *flag.FlagSet) []candidate {
+	if fs == nil {
+		return nil
+	}
+	var candidates []candidate
+	fs.VisitAll(func(f *flag.Flag) {
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		_, usage := flag.UnquoteUsage(f)
+		candidates = append(candidates, candidate{name, usage})
+	})
+	return candidates
+}
+
+// lookupFlag returns the flag named by the argument, such as "-t" or "--type", or nil when
+// the argument is not a flag of the set or carries its value after "=".
+func lookupFlag(fs *flag.FlagSet, arg string) *flag.Flag {
+	if fs == nil || !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return nil
+	}
+	return fs.Lookup(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"))
+}
+
+// isBoolFlag reports whether the flag takes no value, like the flag package checks it.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// countArgs returns the number of positional arguments, skipping the flags and their values.
+func countArgs(fs *flag.FlagSet, args []string) int {
+	n := 0
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			n++
+			continue
+		}
+		if f := lookupFlag(fs, args[i]); f != nil && !isBoolFlag(f) {
+			i++
+		}
+	}
+	return n
+}
+
+// flagValues returns the candidates for the value of a flag of the command: the prefixes
+// and scopes of the git-cm config for the commit command, or the accepted formats.
+func flagValues(cmd, name string) []candidate {
+	switch cmd + " " + name {
+	case "commit t", "commit type":
+		cfg, err := loadSettings(settingsRoot())
+		if err != nil {
+			return nil
+		}
+		var candidates []candidate
+		for _, p := range cfg.Prefixes {
+			candidates = append(candidates, candidate{p.Name, p.Description})
+		}
+		return candidates
+	case "commit scope":
+		cfg, err := loadSettings(settingsRoot())
+		if err != nil {
+			return nil
+		}
+		var candidates []candidate
+		for _, s := range cfg.Scopes {
+			candidates = append(candidates, candidate{Value: s})
+		}
+		return candidates
+	case "lint format":
+		return []candidate{{Value: "text"}, {Value: "json"}}
+	case "config format":
+		return []candidate{{Value: "toml"}, {Value: "yaml"}}
+	}
+	return nil
+}
+
+// argValues returns the candidates for the n-th positional argument of the command, from 0.
+func argValues(cmd string, n int) []candidate {
+	var values []string
+	switch {
+	case cmd == "hook" && n == 0:
+		values = []string{"install", "uninstall"}
+	case cmd == "hook":
+		values = managedHooks
+	case cmd == "completion" && n == 0:
+		values = completionShells
+	case cmd == "help" && n == 0:
+		return commandCandidates()
+	}
+
+	var candidates []candidate
+	for _, v := range values {
+		candidates = append(candidates, candidate{Value: v})
+	}
+	return candidates
+}
+
+// bashCompletion is the completion script for bash. git's own completion calls _git_cm for
+// `git cm`, with the words of the command line in words and the current one at cword.
+const bashCompletion = `# bash completion for git-cm
+# Load it with: source <(git cm completion bash)
+
+# __git_cm_reply sets COMPREPLY to the candidates for the last of its arguments,
+# which are the words following "cm" or "git-cm" on the command line.
+__git_cm_reply() {
+	local IFS=$'\n'
+	COMPREPLY=($(git-cm __complete "$@" 2>/dev/null | cut -f1))
+}
+
+# _git_cm completes "git cm", called by the completion of git.
+_git_cm() {
+	local i start=${__git_cmd_idx:-}
+	if [[ -z $start ]]; then
+		for ((i = 1; i < cword; i++)); do
+			if [[ ${words[i]} == cm ]]; then
+				start=$i
+				break
+			fi
+		done
+	fi
+	[[ -n $start ]] || return
+
+	__git_cm_reply "${words[@]:start+1:cword-start}"
+	# The completion of git does not add a space after the candidates.
+	COMPREPLY=("${COMPREPLY[@]/%/ }")
+}
+
+# _git_cm_command completes the git-cm command.
+_git_cm_command() {
+	__git_cm_reply "${COMP_WORDS[@]:1:COMP_CWORD}"
+}
+
+complete -o bashdefault -o default -F _git_cm_command git-cm
+`
+
+// zshCompletion is the completion script for zsh. The completion of git calls _git-cm for
+// `git cm`, with the words shifted so that words[1] is "cm".
+const zshCompletion = `#compdef git-cm
+# zsh completion for git-cm
+# Load it after compinit with: source <(git cm completion zsh)
+
+_git-cm() {
+	local -a lines candidates
+	local line
+	lines=("${(@f)$(git-cm __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	for line in "${lines[@]}"; do
+		[[ -n $line ]] || continue
+		if [[ $line == *$'\t'* ]]; then
+			candidates+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+		else
+			candidates+=("${line//:/\\:}")
+		fi
+	done
+
+	if (( ${#candidates} )); then
+		_describe -t values 'git cm' candidates
+	else
+		_files
+	fi
+}
+
+compdef _git-cm git-cm
+`
+
+// fishCompletion is the completion script for fish.
+const fishCompletion = `# fish completion for git-cm
+# Load it with: git cm completion fish | source
+
+# Print the candidates for the current token, from the tokens following "cm" or "git-cm".
+function __git_cm_complete
+    set -l args
+    set -l found 0
+    for token in (commandline -opc)
+        if test $found = 1
+            set -a args $token
+        else if test $token = cm; or string match -q -- '*git-cm' $token
+            set found 1
+        end
+    end
+
+    set -l candidates (git-cm __complete $args (commandline -ct) 2>/dev/null)
+    if test (count $candidates) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%s\n' $candidates
+    end
+end
+
+complete -c git-cm -f -a '(__git_cm_complete)'
+complete -c git -n '__fish_seen_subcommand_from cm' -f -a '(__git_cm_complete)'
+`
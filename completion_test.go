@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(origDir)
+	}()
+	unsetRepoEnv(t)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatalf("failed to create .git directory: %v", err)
+	}
+	config := `scopes = ["api", "ui"]
+
+[[prefixes]]
+name = "feat"
+description = "A new feature"
+
+[[prefixes]]
+name = "fix"
+description = "A bug fix"
+
+[[prefixes]]
+name = "perf"
+`
+	if err := os.WriteFile(filepath.Join(root, ".git-cm.toml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	outside := t.TempDir()
+
+	tests := []struct {
+		name     string
+		dir      string
+		words    []string
+		expected []string
+	}{
+		{name: "Commands", words: []string{"l"}, expected: []string{"lint", "log"}},
+		{name: "HiddenCommand", words: []string{"__"}, expected: nil},
+		{name: "Prefixes", words: []string{"-t", ""}, expected: []string{"feat", "fix", "perf"}},
+		{name: "PrefixesOfCommit", words: []string{"commit", "--type", "f"}, expected: []string{"feat", "fix"}},
+		{name: "Scopes", words: []string{"-y", "--scope", ""}, expected: []string{"api", "ui"}},
+		{name: "PrefixesFromDirectory", dir: outside, words: []string{"-C", root, "-t", "p"}, expected: []string{"perf"}},
+		{name: "Directory", words: []string{"-C", ""}, expected: nil},
+		{name: "Flags", words: []string{"--ty"}, expected: []string{"--type"}},
+		{name: "FlagsOfCommand", words: []string{"log", "-"}, expected: []string{"-n"}},
+		{name: "FlagWithoutValue", words: []string{"-y", ""}, expected: nil},
+		{name: "Formats", words: []string{"config", "--format", ""}, expected: []string{"toml", "yaml"}},
+		{name: "HookActions", words: []string{"hook", ""}, expected: []string{"install", "uninstall"}},
+		{name: "Hooks", words: []string{"hook", "install", "c"}, expected: []string{"commit-msg"}},
+		{name: "Shells", words: []string{"completion", ""}, expected: completionShells},
+		{name: "HelpCommands", words: []string{"help", "v"}, expected: []string{"version"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := root
+			if tt.dir != "" {
+				dir = tt.dir
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatalf("failed to change directory: %v", err)
+			}
+
+			var values []string
+			for _, c := range complete(tt.words) {
+				values = append(values, c.Value)
+			}
+			if !reflect.DeepEqual(values, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, values)
+			}
+		})
+	}
+}
+
+func TestComplete_CommitFlags(t *testing.T) {
+	var values []string
+	for _, c := range complete([]string{"-"}) {
+		values = append(values, c.Value)
+	}
+	// Without a command, the flags are those of commit, and -C.
+	for _, flag := range []string{"-C", "--amend", "-t", "--type", "-y", "--yes"} {
+		if !slices.Contains(values, flag) {
+			t.Errorf("expected %s in %q", flag, values)
+		}
+	}
+
+	// -C is only accepted before the command.
+	for _, c := range complete([]string{"commit", "-"}) {
+		if c.Value == "-C" {
+			t.Errorf("expected no -C after the command")
+		}
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		if code := runCompletion([]string{shell}); code != exitOK {
+			t.Errorf("expected exit code %d for %s, got %d", exitOK, shell, code)
+		}
+	}
+	if code := runCompletion([]string{"tcsh"}); code != exitError {
+		t.Errorf("expected exit code %d for an unsupported shell, got %d", exitError, code)
+	}
+	if code := runCompletion(nil); code != exitError {
+		t.Errorf("expected exit code %d without a shell, got %d", exitError, code)
+	}
+}
@@ -1,80 +1,662 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
-	"gopkg.in/ini.v1"
 )
 
+// maxIncludeDepth limits nested include.path and includeIf sections, like git does.
+const maxIncludeDepth = 10
+
 // author holds the name and email of a Git user.
 type author struct {
 	Name  string
 	Email string
 }
 
-// loadGlobalConfig loads the global Git configuration from the user's ~/.gitconfig file.
-// It returns an ini.File object representing the configuration, or an error if it fails.
-func loadGlobalConfig() (*ini.File, error) {
+// String returns the author in the "Name <email>" form used by git trailers.
+func (a author) String() string {
+	return fmt.Sprintf("%s <%s>", a.Name, a.Email)
+}
+
+// configEntry is a single option read from a git config file or from the environment.
+type configEntry struct {
+	key   string // canonical "section.name" or "section.subsection.name", see configKey
+	value string
+}
+
+// gitConfig holds the options of every git config source in the order git reads them:
+// the system, global (XDG, then ~/.gitconfig) and repository files, with their includes
+// expanded in place, followed by GIT_CONFIG_COUNT and `git -c` options from the environment.
+// Later values override earlier ones.
+type gitConfig struct {
+	entries []configEntry
+	gitDir  string // matched by includeIf "gitdir:" conditions
+}
+
+// configKey returns the canonical form of a config key: the section and option names are
+// case-insensitive, while the subsection is case-sensitive.
+func configKey(section, subsection, name string) string {
+	if subsection == "" {
+		return strings.ToLower(section) + "." + strings.ToLower(name)
+	}
+	return strings.ToLower(section) + "." + subsection + "." + strings.ToLower(name)
+}
+
+// get returns the last value of the option, and whether it is set at all.
+func (c *gitConfig) get(section, subsection, name string) (string, bool) {
+	key := configKey(section, subsection, name)
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		if c.entries[i].key == key {
+			return c.entries[i].value, true
+		}
+	}
+	return "", false
+}
+
+// value returns the last value of the option, or an empty string if it is not set.
+func (c *gitConfig) value(section, subsection, name string) string {
+	v, _ := c.get(section, subsection, name)
+	return v
+}
+
+// systemConfigFile returns the path of the system config file, or "" when GIT_CONFIG_NOSYSTEM is set.
+func systemConfigFile() string {
+	if noSystem, _ := parseGitBool(os.Getenv("GIT_CONFIG_NOSYSTEM")); noSystem {
+		return ""
+	}
+	if p := os.Getenv("GIT_CONFIG_SYSTEM"); p != "" {
+		return p
+	}
+	return "/etc/gitconfig"
+}
+
+// globalConfigFiles returns the paths of the global config files in the order git reads them.
+func globalConfigFiles() ([]string, error) {
+	if p := os.Getenv("GIT_CONFIG_GLOBAL"); p != "" {
+		return []string{p}, nil
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	cfgPath := filepath.Join(homeDir, ".gitconfig")
-	cfg, err := ini.Load(cfgPath)
+	xdgDir := os.Getenv("XDG_CONFIG_HOME")
+	if xdgDir == "" {
+		xdgDir = filepath.Join(homeDir, ".config")
+	}
+	return []string{filepath.Join(xdgDir, "git", "config"), filepath.Join(homeDir, ".gitconfig")}, nil
+}
+
+// loadGitConfig reads every git config source for the repository whose git directory is gitDir.
+// An empty gitDir only reads the system and global configuration. Missing files are skipped.
+func loadGitConfig(gitDir string) (*gitConfig, error) {
+	c := &gitConfig{gitDir: gitDir}
+
+	files := []string{systemConfigFile()}
+	global, err := globalConfigFiles()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load global config file: %w", err)
+		return nil, err
+	}
+	files = append(files, global...)
+	if gitDir != "" {
+		files = append(files, filepath.Join(gitDir, "config"))
 	}
-	return cfg, nil
+
+	for _, p := range files {
+		if p == "" {
+			continue
+		}
+		if err := c.readFile(p, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.readEnv(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-// loadGlobalAuthor retrieves the user's name and email from the [user] section of the global
-// Git configuration (stored in ~/.gitconfig). It returns an author pointer populated with the
-// retrieved data, or an error if any required information is missing.
-func loadGlobalAuthor() (*author, error) {
-	cfg, err := loadGlobalConfig()
+// repoConfig loads the git configuration of the repository, which is shared by its worktrees.
+func repoConfig(repo *git.Repository) (*gitConfig, error) {
+	dir, err := commonDir(repo)
 	if err != nil {
 		return nil, err
 	}
+	return loadGitConfig(dir)
+}
+
+// readFile appends the options of the config file at p, expanding its includes in place.
+// A missing file is not an error.
+func (c *gitConfig) readFile(p string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("exceeded maximum include depth (%d) while including %s", maxIncludeDepth, p)
+	}
 
-	userSection, err := cfg.GetSection("user")
+	data, err := os.ReadFile(p)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get [user] section: %w", err)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read git config %s: %w", p, err)
 	}
 
-	nameKey, err := userSection.GetKey("name")
+	entries, err := parseGitConfig(string(data))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user.name key: %w", err)
+		return fmt.Errorf("failed to parse git config %s: %w", p, err)
 	}
 
-	emailKey, err := userSection.GetKey("email")
+	for _, e := range entries {
+		c.entries = append(c.entries, e)
+
+		include, err := c.includePath(e, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		if include != "" {
+			if err := c.readFile(include, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includePath returns the file included by an include.path or includeIf.<condition>.path entry
+// whose condition holds, resolved against dir, the directory of the including file.
+// It returns an empty string for any other entry.
+func (c *gitConfig) includePath(e configEntry, dir string) (string, error) {
+	var p string
+	switch {
+	case e.key == "include.path":
+		p = e.value
+	case strings.HasPrefix(e.key, "includeif.") && strings.HasSuffix(e.key, ".path"):
+		condition := strings.TrimSuffix(strings.TrimPrefix(e.key, "includeif."), ".path")
+		ok, err := c.matchIncludeCondition(condition, dir)
+		if err != nil || !ok {
+			return "", err
+		}
+		p = e.value
+	default:
+		return "", nil
+	}
+
+	if p == "" {
+		return "", nil
+	}
+	p, err := expandHomeDir(p)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	return p, nil
+}
+
+// matchIncludeCondition reports whether the includeIf condition holds. Only the gitdir: and
+// gitdir/i: conditions are supported; the others never match, as in older versions of git.
+func (c *gitConfig) matchIncludeCondition(condition, dir string) (bool, error) {
+	var pattern string
+	var foldCase bool
+	switch {
+	case strings.HasPrefix(condition, "gitdir:"):
+		pattern = strings.TrimPrefix(condition, "gitdir:")
+	case strings.HasPrefix(condition, "gitdir/i:"):
+		pattern = strings.TrimPrefix(condition, "gitdir/i:")
+		foldCase = true
+	default:
+		return false, nil
+	}
+	if c.gitDir == "" || pattern == "" {
+		return false, nil
+	}
+	// A trailing slash matches everything inside the directory.
+	dirPattern := strings.HasSuffix(pattern, "/")
+
+	switch {
+	case strings.HasPrefix(pattern, "~/"):
+		p, err := expandHomeDir(pattern)
+		if err != nil {
+			return false, err
+		}
+		pattern = p
+	case strings.HasPrefix(pattern, "./"):
+		pattern = filepath.Join(dir, pattern[2:])
+	case !filepath.IsAbs(pattern):
+		pattern = "**/" + pattern
+	}
+	if dirPattern {
+		pattern = strings.TrimSuffix(pattern, "/") + "/**"
+	}
+	pattern = filepath.ToSlash(pattern)
+
+	re := globPattern(pattern)
+	if foldCase {
+		re = "(?i)" + re
+	}
+	matcher, err := regexp.Compile(re)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user.email key: %w", err)
+		return false, fmt.Errorf("invalid includeIf pattern %q: %w", condition, err)
+	}
+
+	candidates := []string{c.gitDir}
+	if resolved, err := filepath.EvalSymlinks(c.gitDir); err == nil {
+		candidates = append(candidates, resolved)
+	}
+	for _, candidate := range candidates {
+		if matcher.MatchString(filepath.ToSlash(candidate)) {
+			return true, nil
+		}
 	}
-	return &author{
-		Name:  nameKey.String(),
-		Email: emailKey.String(),
-	}, nil
+	return false, nil
 }
 
-// getAuthorInfo obtains author information from the repository's configuration.
-// If the repository configuration does not provide user information,
-// the function falls back to loading the global Git configuration.
-// It returns an author pointer containing the name and email, or an error.
+// globPattern converts a wildmatch pattern, as used by includeIf "gitdir:", to an anchored
+// regular expression: "**/" matches any number of directories, "**" anything,
+// "*" anything but a slash and "?" a single character other than a slash.
+func globPattern(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// readEnv appends the options given in the environment: GIT_CONFIG_COUNT with
+// GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n>, then GIT_CONFIG_PARAMETERS, which git
+// sets for `git -c key=value cm`.
+func (c *gitConfig) readEnv() error {
+	if s := os.Getenv("GIT_CONFIG_COUNT"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid GIT_CONFIG_COUNT %q", s)
+		}
+		for i := range n {
+			key, ok := os.LookupEnv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i))
+			if !ok {
+				return fmt.Errorf("missing config key GIT_CONFIG_KEY_%d", i)
+			}
+			value, ok := os.LookupEnv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i))
+			if !ok {
+				return fmt.Errorf("missing config value GIT_CONFIG_VALUE_%d", i)
+			}
+			if err := c.addParameter(key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	params, err := parseConfigParameters(os.Getenv("GIT_CONFIG_PARAMETERS"))
+	if err != nil {
+		return fmt.Errorf("invalid GIT_CONFIG_PARAMETERS: %w", err)
+	}
+	for _, p := range params {
+		if err := c.addParameter(p[0], p[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addParameter appends the option named by a dotted "section[.subsection].name" key.
+func (c *gitConfig) addParameter(key, value string) error {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return fmt.Errorf("invalid config key %q", key)
+	}
+
+	subsection := ""
+	if first != last {
+		subsection = key[first+1 : last]
+	}
+	c.entries = append(c.entries, configEntry{key: configKey(key[:first], subsection, key[last+1:]), value: value})
+	return nil
+}
+
+// parseConfigParameters parses GIT_CONFIG_PARAMETERS, a list of shell-quoted options in either
+// the 'key'='value' form or the older 'key=value' form. It returns [key, value] pairs.
+func parseConfigParameters(s string) ([][2]string, error) {
+	var params [][2]string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return params, nil
+		}
+
+		key, rest, err := unquoteShellWord(s)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(rest, "=") {
+			value, r, err := unquoteShellWord(rest[1:])
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, [2]string{key, value})
+			s = r
+			continue
+		}
+
+		k, v, ok := strings.Cut(key, "=")
+		if !ok {
+			// A bare key is a boolean set to true.
+			v = "true"
+		}
+		params = append(params, [2]string{k, v})
+		s = rest
+	}
+}
+
+// unquoteShellWord reads a single-quoted word from the start of s, in which a quote is written
+// by closing the word, adding an escaped quote and reopening it. It returns the word and the rest of s.
+func unquoteShellWord(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "'") {
+		return "", "", fmt.Errorf("expected a quoted word at %q", s)
+	}
+
+	var b strings.Builder
+	s = s[1:]
+	for {
+		i := strings.Index(s, "'")
+		if i < 0 {
+			return "", "", fmt.Errorf("unterminated quoted word")
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+		if !strings.HasPrefix(s, `\''`) {
+			return b.String(), s, nil
+		}
+		b.WriteString("'")
+		s = s[3:]
+	}
+}
+
+// parseGitConfig parses the content of a git config file into its options, in file order.
+// An option without a value (a bare "name") is a boolean set to true.
+func parseGitConfig(content string) ([]configEntry, error) {
+	var entries []configEntry
+	var section, subsection string
+
+	r := bufio.NewReader(strings.NewReader(content))
+	lineNo := 0
+	for {
+		line, err := r.ReadString('\n')
+		if line == "" && err != nil {
+			return entries, nil
+		}
+		lineNo++
+
+		s := strings.TrimSpace(line)
+		if s == "" || s[0] == '#' || s[0] == ';' {
+			continue
+		}
+
+		if s[0] == '[' {
+			var rest string
+			section, subsection, rest, err = parseSectionHeader(s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			s = strings.TrimSpace(rest)
+			if s == "" || s[0] == '#' || s[0] == ';' {
+				continue
+			}
+		}
+
+		if section == "" {
+			return nil, fmt.Errorf("line %d: option outside of a section", lineNo)
+		}
+
+		name, raw, hasValue := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !isConfigName(name) {
+			return nil, fmt.Errorf("line %d: invalid option name %q", lineNo, name)
+		}
+		if !hasValue {
+			entries = append(entries, configEntry{key: configKey(section, subsection, name), value: "true"})
+			continue
+		}
+
+		// A backslash at the end of a line continues the value on the next one.
+		for strings.HasSuffix(strings.TrimRight(raw, "\r\n"), `\`) && !strings.HasSuffix(strings.TrimRight(raw, "\r\n"), `\\`) {
+			next, err := r.ReadString('\n')
+			if next == "" && err != nil {
+				break
+			}
+			lineNo++
+			raw = strings.TrimSuffix(strings.TrimRight(raw, "\r\n"), `\`) + next
+		}
+
+		value, err := parseConfigValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		entries = append(entries, configEntry{key: configKey(section, subsection, name), value: value})
+	}
+}
+
+// parseSectionHeader parses a `[section]`, `[section "subsection"]` or legacy `[section.subsection]`
+// header at the start of s, and returns the rest of the line.
+func parseSectionHeader(s string) (section, subsection, rest string, err error) {
+	end := strings.Index(s, "]")
+	if i := strings.Index(s, `"`); i >= 0 && i < end {
+		// The subsection may contain "]", so look for the end after the quoted part.
+		header := s[1:i]
+		section = strings.TrimSpace(header)
+
+		var b strings.Builder
+		j := i + 1
+		for ; j < len(s) && s[j] != '"'; j++ {
+			if s[j] == '\\' && j+1 < len(s) {
+				j++
+			}
+			b.WriteByte(s[j])
+		}
+		if j >= len(s) || !strings.HasPrefix(strings.TrimLeft(s[j+1:], " \t"), "]") {
+			return "", "", "", fmt.Errorf("invalid section header %q", s)
+		}
+		subsection = b.String()
+		rest = strings.TrimLeft(s[j+1:], " \t")[1:]
+	} else {
+		if end < 0 {
+			return "", "", "", fmt.Errorf("invalid section header %q", s)
+		}
+		section, rest = s[1:end], s[end+1:]
+		if i := strings.Index(section, "."); i >= 0 {
+			// Legacy subsections are case-insensitive.
+			section, subsection = section[:i], strings.ToLower(section[i+1:])
+		}
+	}
+
+	if !isConfigName(section) {
+		return "", "", "", fmt.Errorf("invalid section name %q", section)
+	}
+	return section, subsection, rest, nil
+}
+
+// isConfigName reports whether s is a valid section or option name.
+func isConfigName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r == '-' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseConfigValue unquotes an option value: double quotes preserve whitespace and comment
+// characters, backslash escapes are expanded, and unquoted comments and trailing whitespace are removed.
+func parseConfigValue(raw string) (string, error) {
+	var b strings.Builder
+	quoted := false
+	// pending holds unquoted whitespace that is only kept if more of the value follows it.
+	pending := ""
+	started := false
+
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		switch {
+		case ch == '\n' || ch == '\r':
+			if quoted {
+				return "", fmt.Errorf("unterminated quoted value")
+			}
+			return b.String(), nil
+		case !quoted && (ch == ' ' || ch == '\t'):
+			if started {
+				pending += string(ch)
+			}
+			continue
+		case !quoted && (ch == '#' || ch == ';'):
+			return b.String(), nil
+		}
+
+		b.WriteString(pending)
+		pending = ""
+		started = true
+
+		switch ch {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+			if i >= len(raw) {
+				return "", fmt.Errorf("incomplete escape sequence")
+			}
+			switch raw[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case '"', '\\':
+				b.WriteByte(raw[i])
+			default:
+				return "", fmt.Errorf("invalid escape sequence \\%c", raw[i])
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+
+	if quoted {
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+	return b.String(), nil
+}
+
+// identity returns the identity git uses for kind ("author" or "committer"): the GIT_<KIND>_NAME
+// and GIT_<KIND>_EMAIL environment variables, then the <kind>.name and <kind>.email options,
+// then user.name and user.email, and for the email the EMAIL environment variable.
+func (c *gitConfig) identity(kind string) (*author, error) {
+	env := "GIT_" + strings.ToUpper(kind) + "_"
+
+	name, ok := os.LookupEnv(env + "NAME")
+	if !ok {
+		if name, ok = c.get(kind, "", "name"); !ok {
+			name = c.value("user", "", "name")
+		}
+	}
+
+	email, ok := os.LookupEnv(env + "EMAIL")
+	if !ok {
+		if email, ok = c.get(kind, "", "email"); !ok {
+			if email, ok = c.get("user", "", "email"); !ok {
+				email = os.Getenv("EMAIL")
+			}
+		}
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("%s identity unknown: set user.name in the git config or %sNAME", kind, env)
+	}
+	if email == "" {
+		return nil, fmt.Errorf("%s identity unknown: set user.email in the git config or %sEMAIL", kind, env)
+	}
+	return &author{Name: name, Email: email}, nil
+}
+
+// getAuthorInfo returns the author `git commit` would use in the repository,
+// resolved from the environment and the layered git configuration.
 func getAuthorInfo(repo *git.Repository) (*author, error) {
-	cfg, err := repo.Config()
+	cfg, err := repoConfig(repo)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.identity("author")
+}
+
+// getCommitterInfo returns the committer `git commit` would use in the repository,
+// resolved from the environment and the layered git configuration.
+func getCommitterInfo(repo *git.Repository) (*author, error) {
+	cfg, err := repoConfig(repo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get repository config: %w", err)
+		return nil, err
 	}
+	return cfg.identity("committer")
+}
 
-	if cfg.User.Name != "" && cfg.User.Email != "" {
-		return &author{
-			Name:  cfg.User.Name,
-			Email: cfg.User.Email,
-		}, nil
+// gitConfigValue returns the effective value of the section.subsection.key option for the
+// repository. An empty subsection reads the option directly from the section.
+// It returns an empty string if the option is not set anywhere.
+func gitConfigValue(repo *git.Repository, section, subsection, key string) (string, error) {
+	cfg, err := repoConfig(repo)
+	if err != nil {
+		return "", err
+	}
+	return cfg.value(section, subsection, key), nil
+}
+
+// parseGitBool parses a git config boolean. Like git, it accepts true/yes/on/1 and
+// false/no/off/0 in any case; an empty value is false.
+func parseGitBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "", "false", "no", "off", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
+
+// expandHomeDir expands a leading "~/" in a path from the git config to the user's home directory.
+func expandHomeDir(p string) (string, error) {
+	if !strings.HasPrefix(p, "~/") {
+		return p, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
 	}
-	return loadGlobalAuthor()
+	return filepath.Join(homeDir, p[2:]), nil
 }
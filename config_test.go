@@ -3,119 +3,176 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/go-git/go-git/v5"
 )
 
-func TestLoadGlobalConfig(t *testing.T) {
+// isolateGitConfig points the system and global git config at an empty temporary home directory
+// and clears the identity environment variables, so that tests do not read the user's config.
+func isolateGitConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	t.Setenv("GIT_CONFIG_PARAMETERS", "")
+	t.Setenv("EMAIL", "")
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		unsetenv(t, name)
+	}
+	return home
+}
+
+// unsetenv unsets the environment variable for the duration of the test.
+func unsetenv(t *testing.T, name string) {
+	t.Helper()
+	t.Setenv(name, "")
+	if err := os.Unsetenv(name); err != nil {
+		t.Fatalf("failed to unset %s: %v", name, err)
+	}
+}
+
+func TestParseGitConfig(t *testing.T) {
 	tests := []struct {
-		name          string
-		configContent string
-		expectErr     bool
+		name      string
+		content   string
+		expected  []configEntry
+		expectErr bool
 	}{
 		{
-			name:          "ValidConfiguration",
-			configContent: "[user]\nname = GlobalUser\nemail = global@example.com\n",
-			expectErr:     false,
+			name:    "Sections",
+			content: "[User]\n\tName = Tester\n[remote \"Origin\"]\n\turl = https://example.com\n[Branch.Main]\nremote = origin\n",
+			expected: []configEntry{
+				{key: "user.name", value: "Tester"},
+				{key: "remote.Origin.url", value: "https://example.com"},
+				{key: "branch.main.remote", value: "origin"},
+			},
+		},
+		{
+			name:    "Values",
+			content: "[core]\n# comment\n\tbare\n\tpager = less -R ; comment\n\tquoted = \" a ; b \" # comment\n\tescaped = a\\tb\\\\c\n\tcontinued = a \\\n b\n",
+			expected: []configEntry{
+				{key: "core.bare", value: "true"},
+				{key: "core.pager", value: "less -R"},
+				{key: "core.quoted", value: " a ; b "},
+				{key: "core.escaped", value: "a\tb\\c"},
+				{key: "core.continued", value: "a  b"},
+			},
+		},
+		{
+			name:      "OptionOutsideSection",
+			content:   "name = Tester\n",
+			expectErr: true,
 		},
 		{
-			name:          "EmptyConfigurationFile",
-			configContent: "",
-			expectErr:     false,
+			name:      "UnterminatedQuote",
+			content:   "[user]\nname = \"Tester\n",
+			expectErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tempDir := t.TempDir()
-			t.Setenv("HOME", tempDir)
-
-			cfgPath := filepath.Join(tempDir, ".gitconfig")
-			if err := os.WriteFile(cfgPath, []byte(tt.configContent), 0644); err != nil {
-				t.Fatalf("failed to write .gitconfig: %v", err)
-			}
-
-			cfg, err := loadGlobalConfig()
+			entries, err := parseGitConfig(tt.content)
 			if tt.expectErr {
 				if err == nil {
 					t.Error("expected error, but got nil")
 				}
 				return
 			}
-
 			if err != nil {
-				t.Errorf("unexpected error: %v", err)
+				t.Fatalf("unexpected error: %v", err)
 			}
-
-			if cfg == nil {
-				t.Error("expected non-nil config")
+			if !slices.Equal(entries, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, entries)
 			}
 		})
 	}
 }
 
-func TestLoadGlobalAuthor(t *testing.T) {
+func TestLoadGitConfig(t *testing.T) {
+	home := isolateGitConfig(t)
+
+	write := func(p, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", p, err)
+		}
+	}
+
+	gitDir := filepath.Join(home, "work", "project", ".git")
+	write(filepath.Join(home, ".config", "git", "config"), "[user]\n\tname = XDG\n\temail = xdg@example.com\n[core]\n\teditor = vi\n")
+	write(filepath.Join(home, ".gitconfig"), `[user]
+	name = Global
+[include]
+	path = .gitconfig.local
+[includeIf "gitdir:~/work/"]
+	path = ~/.gitconfig.work
+[includeIf "gitdir:~/other/"]
+	path = ~/.gitconfig.other
+[commit]
+	gpgsign = false
+`)
+	write(filepath.Join(home, ".gitconfig.local"), "[commit]\n\tgpgsign = true\n[core]\n\tpager = less\n")
+	write(filepath.Join(home, ".gitconfig.work"), "[user]\n\temail = work@example.com\n")
+	write(filepath.Join(home, ".gitconfig.other"), "[user]\n\temail = other@example.com\n")
+	write(filepath.Join(gitDir, "config"), "[core]\n\tpager = more\n")
+	t.Setenv("GIT_CONFIG_PARAMETERS", "'core.editor'='nano' 'color.ui=never'")
+
+	cfg, err := loadGitConfig(gitDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
 	tests := []struct {
-		name          string
-		configContent string
-		expectedName  string
-		expectedEmail string
-		expectError   bool
+		key      [3]string
+		expected string
 	}{
-		{
-			name:          "ValidConfig",
-			configContent: "[user]\nname = GlobalUser\nemail = global@example.com\n",
-			expectedName:  "GlobalUser",
-			expectedEmail: "global@example.com",
-			expectError:   false,
-		},
-		{
-			name:          "MissingUserSection",
-			configContent: "[other]\nkey = value\n",
-			expectError:   true,
-		},
-		{
-			name:          "MissingNameKey",
-			configContent: "[user]\nemail = global@example.com\n",
-			expectError:   true,
-		},
-		{
-			name:          "MissingEmailKey",
-			configContent: "[user]\nname = GlobalUser\n",
-			expectError:   true,
-		},
+		{key: [3]string{"user", "", "name"}, expected: "Global"},
+		{key: [3]string{"user", "", "email"}, expected: "work@example.com"},
+		{key: [3]string{"commit", "", "gpgsign"}, expected: "false"},
+		{key: [3]string{"core", "", "pager"}, expected: "more"},
+		{key: [3]string{"core", "", "editor"}, expected: "nano"},
+		{key: [3]string{"color", "", "ui"}, expected: "never"},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			tempDir := t.TempDir()
-			t.Setenv("HOME", tempDir)
+		if got := cfg.value(tt.key[0], tt.key[1], tt.key[2]); got != tt.expected {
+			t.Errorf("expected %s.%s to be %q, got %q", tt.key[0], tt.key[2], tt.expected, got)
+		}
+	}
+}
 
-			cfgPath := filepath.Join(tempDir, ".gitconfig")
-			if err := os.WriteFile(cfgPath, []byte(tt.configContent), 0644); err != nil {
-				t.Fatalf("failed to write .gitconfig: %v", err)
-			}
+func TestLoadGitConfig_IncludeCycle(t *testing.T) {
+	home := isolateGitConfig(t)
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[include]\n\tpath = .gitconfig\n"), 0644); err != nil {
+		t.Fatalf("failed to write .gitconfig: %v", err)
+	}
 
-			aut, err := loadGlobalAuthor()
-			if tt.expectError {
-				if err == nil {
-					t.Error("expected error but got nil")
-				}
-				return
-			}
+	if _, err := loadGitConfig(""); err == nil {
+		t.Error("expected error for an include cycle, but got nil")
+	}
+}
 
-			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-			}
+func TestParseConfigParameters(t *testing.T) {
+	params, err := parseConfigParameters(`'user.name'='O'\''Brien' 'core.bare' 'color.ui=auto'`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
-			if aut.Name != tt.expectedName {
-				t.Errorf("expected name %q, got %q", tt.expectedName, aut.Name)
-			}
-			if aut.Email != tt.expectedEmail {
-				t.Errorf("expected email %q, got %q", tt.expectedEmail, aut.Email)
-			}
-		})
+	expected := [][2]string{{"user.name", "O'Brien"}, {"core.bare", "true"}, {"color.ui", "auto"}}
+	if !slices.Equal(params, expected) {
+		t.Errorf("expected %q, got %q", expected, params)
+	}
+
+	if _, err := parseConfigParameters("'unterminated"); err == nil {
+		t.Error("expected error for an unterminated word, but got nil")
 	}
 }
 
@@ -124,6 +181,7 @@ func TestGetAuthorInfo(t *testing.T) {
 		name                string
 		repoSetup           func(t *testing.T, repoDir string)
 		globalConfigContent string
+		env                 map[string]string
 		expectedName        string
 		expectedEmail       string
 		expectError         bool
@@ -158,6 +216,33 @@ func TestGetAuthorInfo(t *testing.T) {
 			expectedEmail:       "global@example.com",
 			expectError:         false,
 		},
+		{
+			name: "RepositoryNameGlobalEmail",
+			repoSetup: func(t *testing.T, repoDir string) {
+				repo, err := git.PlainOpen(repoDir)
+				if err != nil {
+					t.Fatalf("failed to open repository: %v", err)
+				}
+				cfg, err := repo.Config()
+				if err != nil {
+					t.Fatalf("failed to get repository config: %v", err)
+				}
+				cfg.User.Name = "RepoUser"
+				if err := repo.Storer.SetConfig(cfg); err != nil {
+					t.Fatalf("failed to set repository config: %v", err)
+				}
+			},
+			globalConfigContent: "[user]\nname = GlobalUser\nemail = global@example.com\n",
+			expectedName:        "RepoUser",
+			expectedEmail:       "global@example.com",
+		},
+		{
+			name:                "AuthorSectionAndEnvironment",
+			globalConfigContent: "[user]\nname = GlobalUser\nemail = global@example.com\n[author]\nemail = author@example.com\n",
+			env:                 map[string]string{"GIT_AUTHOR_NAME": "EnvUser"},
+			expectedName:        "EnvUser",
+			expectedEmail:       "author@example.com",
+		},
 		{
 			name:                "FallbackFailsDueToInvalidGlobalConfig",
 			repoSetup:           func(t *testing.T, repoDir string) {},
@@ -174,8 +259,10 @@ func TestGetAuthorInfo(t *testing.T) {
 				t.Fatalf("failed to initialize repository: %v", err)
 			}
 
-			globalHome := t.TempDir()
-			t.Setenv("HOME", globalHome)
+			globalHome := isolateGitConfig(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
 			cfgPath := filepath.Join(globalHome, ".gitconfig")
 			if err := os.WriteFile(cfgPath, []byte(tt.globalConfigContent), 0644); err != nil {
 				t.Fatalf("failed to write global .gitconfig: %v", err)
@@ -207,3 +294,36 @@ func TestGetAuthorInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCommitterInfo(t *testing.T) {
+	home := isolateGitConfig(t)
+	content := "[user]\nname = GlobalUser\nemail = global@example.com\n[committer]\nname = Committer\n"
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write global .gitconfig: %v", err)
+	}
+	t.Setenv("GIT_AUTHOR_EMAIL", "author@example.com")
+	t.Setenv("GIT_COMMITTER_EMAIL", "committer@example.com")
+
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %v", err)
+	}
+
+	c, err := getCommitterInfo(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := author{Name: "Committer", Email: "committer@example.com"}
+	if *c != expected {
+		t.Errorf("expected committer %+v, got %+v", expected, *c)
+	}
+
+	a, err := getAuthorInfo(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = author{Name: "GlobalUser", Email: "author@example.com"}
+	if *a != expected {
+		t.Errorf("expected author %+v, got %+v", expected, *a)
+	}
+}
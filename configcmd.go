@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configOptions holds the flags of `git cm config`.
+type configOptions struct {
+	Files  bool
+	Format string
+}
+
+// newConfigFlagSet returns the flag set of the config command, storing the flags in o.
+func newConfigFlagSet(o *configOptions) *flag.FlagSet {
+	fs := newFlagSet("config")
+	fs.BoolVar(&o.Files, "files", false, "List the config files read, from the lowest precedence")
+	fs.StringVar(&o.Format, "format", "toml", "Output `FORMAT`: toml or yaml")
+	return fs
+}
+
+// runConfig implements `git cm config`, which prints the configuration used in the current
+// repository, the config files merged with the defaults, or lists the files read with --files.
+func runConfig(args []string) int {
+	o := &configOptions{}
+	fs := newConfigFlagSet(o)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return exitWithError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if o.Format != "toml" && o.Format != "yaml" {
+		return exitWithError(fmt.Errorf("unknown format %q", o.Format))
+	}
+
+	root := settingsRoot()
+	if o.Files {
+		paths, err := settingsFiles(root)
+		if err != nil {
+			return exitWithError(err)
+		}
+		for _, p := range paths {
+			fmt.Println(p)
+		}
+		return exitOK
+	}
+
+	cfg, err := loadSettings(root)
+	if err != nil {
+		return exitWithError(err)
+	}
+	if err := writeSettings(os.Stdout, cfg, o.Format); err != nil {
+		return exitWithError(err)
+	}
+	return exitOK
+}
+
+// settingsRoot returns the root of the current repository, or "." outside a repository,
+// where only the current directory is searched for a repository config file.
+func settingsRoot() string {
+	if loc, err := findRepo(); err == nil {
+		return loc.Root
+	}
+	return "."
+}
+
+// writeSettings writes the configuration in the format of a config file, toml or yaml.
+func writeSettings(w io.Writer, s *settings, format string) error {
+	if format == "yaml" {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(s); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		return enc.Close()
+	}
+
+	if err := toml.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return nil
+}
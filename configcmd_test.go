@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteSettings(t *testing.T) {
+	cfg := defaultSettings()
+	cfg.Scopes = []string{"api", "ui"}
+	cfg.Suggest = []suggestRule{{Paths: []string{"migrations/**"}, Scope: "db"}}
+	cfg.Templates = []commitTemplate{{Name: "bugfix", Body: "Cause:\n\nFix:\n"}}
+	cfg.Lint = map[string]lintRuleConfig{
+		"header-max-length": {Severity: severityWarning, Value: 72, Types: []string{"feat"}},
+	}
+
+	for _, format := range []string{"toml", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeSettings(&buf, cfg, format); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The output is a config file giving back the same configuration.
+			p := filepath.Join(t.TempDir(), "config."+format)
+			if err := os.WriteFile(p, buf.Bytes(), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+			decoded, err := readSettingsFile(p)
+			if err != nil {
+				t.Fatalf("failed to read the output back: %v\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(decoded, cfg) {
+				t.Errorf("expected %+v, got %+v", cfg, decoded)
+			}
+		})
+	}
+}
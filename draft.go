@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// draftSaveInterval is how often the TUI saves the message being written as a draft.
+const draftSaveInterval = 2 * time.Second
+
+// draft is the message being written on a branch, saved so that it survives a crash,
+// a quit or a failed commit.
+type draft struct {
+	Time    time.Time      `json:"time"`
+	Message *commitMessage `json:"message"`
+}
+
+// draftStore saves the draft of a branch in .git/git-cm/drafts/<branch>.json.
+type draftStore struct {
+	path string
+}
+
+// newDraftStore returns the draftStore of the branch checked out in the repository.
+// A detached HEAD has a single draft of its own.
+func newDraftStore(repo *git.Repository) (*draftStore, error) {
+	dir, err := dataDir(repo)
+	if err != nil {
+		return nil, err
+	}
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+	if branch == "" {
+		branch = "HEAD"
+	}
+	// Branch names may contain slashes, which are escaped to keep one file per branch.
+	return &draftStore{path: filepath.Join(dir, "drafts", url.PathEscape(branch)+".json")}, nil
+}
+
+// load returns the saved draft, or nil when there is none.
+func (s *draftStore) load() (*draft, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read draft: %w", err)
+	}
+
+	d := &draft{}
+	if err := json.Unmarshal(data, d); err != nil || d.Message == nil {
+		return nil, fmt.Errorf("failed to read draft %s: invalid content", s.path)
+	}
+	return d, nil
+}
+
+// save replaces the draft with the message.
+func (s *draftStore) save(msg *commitMessage) error {
+	data, err := json.Marshal(draft{Time: time.Now(), Message: msg})
+	if err != nil {
+		return fmt.Errorf("failed to encode draft: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to save draft: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to save draft: %w", err)
+	}
+	return nil
+}
+
+// clear removes the draft.
+func (s *draftStore) clear() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove draft: %w", err)
+	}
+	return nil
+}
+
+// keepDraft saves the message as the draft, or removes the draft when the message is empty.
+// Failures only print a warning since the draft is a convenience. A nil store keeps no draft.
+func keepDraft(s *draftStore, msg *commitMessage) {
+	if s == nil {
+		return
+	}
+
+	var err error
+	if msg == nil || msg.empty() {
+		err = s.clear()
+	} else {
+		err = s.save(msg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// keepQuitDraft keeps the message of a TUI quit without committing as the draft, like keepDraft.
+// When the TUI was quit at the offer to restore the draft, the offered draft is left as is.
+func keepQuitDraft(s *draftStore, res tuiResult) {
+	if res.DraftOffered {
+		return
+	}
+	keepDraft(s, res.Message)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestNewDraftStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     plumbing.ReferenceName
+		expected string
+	}{
+		{name: "Branch", head: "refs/heads/main", expected: "main.json"},
+		{name: "BranchWithSlash", head: "refs/heads/feature/login", expected: "feature%2Flogin.json"},
+		{name: "Detached", expected: "HEAD.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			repo, err := git.PlainInit(root, false)
+			if err != nil {
+				t.Fatalf("failed to init repo: %v", err)
+			}
+			head := plumbing.NewHashReference(plumbing.HEAD, plumbing.ZeroHash)
+			if tt.head != "" {
+				head = plumbing.NewSymbolicReference(plumbing.HEAD, tt.head)
+			}
+			if err := repo.Storer.SetReference(head); err != nil {
+				t.Fatalf("failed to set HEAD: %v", err)
+			}
+
+			s, err := newDraftStore(repo)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if expected := filepath.Join(root, ".git", "git-cm", "drafts", tt.expected); s.path != expected {
+				t.Errorf("expected %q, got %q", expected, s.path)
+			}
+		})
+	}
+}
+
+func TestDraftStore(t *testing.T) {
+	s := &draftStore{path: filepath.Join(t.TempDir(), "drafts", "main.json")}
+
+	d, err := s.load()
+	if err != nil || d != nil {
+		t.Fatalf("expected no draft, got %+v, %v", d, err)
+	}
+
+	msg := &commitMessage{Prefix: "fix", Scope: "api", Summary: "handle nil", Description: "body"}
+	keepDraft(s, msg)
+	d, err = s.load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d == nil || d.Message.String() != msg.String() || d.Time.IsZero() {
+		t.Fatalf("expected draft %q, got %+v", msg.String(), d)
+	}
+
+	// An empty message removes the draft.
+	keepDraft(s, &commitMessage{Prefix: "feat"})
+	if d, err = s.load(); err != nil || d != nil {
+		t.Errorf("expected the draft to be removed, got %+v, %v", d, err)
+	}
+}
@@ -6,11 +6,47 @@ import (
 	"os"
 )
 
-// errQuit is a sentinel error returned when the user selects Quit.
-var errQuit = errors.New("quit selected")
+// Exit codes returned by git-cm.
+const (
+	exitOK             = 0
+	exitError          = 1
+	exitInvalidMessage = 2
+	exitNothingStaged  = 3
+	exitCommitFailed   = 4
+)
+
+var (
+	// errQuit is a sentinel error returned when the user selects Quit.
+	errQuit = errors.New("quit selected")
+	// errInvalidMessage is wrapped by errors describing why a commit message was rejected.
+	errInvalidMessage = errors.New("invalid commit message")
+	// errNothingStaged is returned when there are no staged changes to commit.
+	errNothingStaged = errors.New("no files are staged")
+	// errCommitFailed is wrapped by errors returned when creating the commit object fails.
+	errCommitFailed = errors.New("failed to commit")
+	// errUsage wraps the errors of invalid flags, which the flag package reports with the usage.
+	errUsage = errors.New("invalid usage")
+	// errBareRepository is wrapped by errors returned when the repository has no worktree to commit from.
+	errBareRepository = errors.New("cannot commit in a bare repository")
+)
+
+// exitCode returns the status code corresponding to err.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errInvalidMessage):
+		return exitInvalidMessage
+	case errors.Is(err, errNothingStaged):
+		return exitNothingStaged
+	case errors.Is(err, errCommitFailed):
+		return exitCommitFailed
+	default:
+		return exitError
+	}
+}
 
-// exitWithError prints the error message to stderr and returns a non-zero status code.
+// exitWithError prints the error message to stderr and returns the non-zero status code
+// corresponding to the error.
 func exitWithError(err error) int {
 	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-	return 1
+	return exitCode(err)
 }
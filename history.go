@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// maxHistoryEntries is the number of messages kept in the history, the oldest being dropped first.
+const maxHistoryEntries = 100
+
+// historyFile is the name of the message history file in the git-cm data directory.
+const historyFile = "history.jsonl"
+
+// historyEntry is a commit message saved in the history: a draft left when the TUI was quit or
+// the commit failed, or the message of a created commit.
+type historyEntry struct {
+	Time    time.Time      `json:"time"`
+	Hash    string         `json:"hash,omitempty"` // the created commit; empty for a draft
+	Message *commitMessage `json:"message"`
+}
+
+// draft reports whether the message was not committed.
+func (e historyEntry) draft() bool {
+	return e.Hash == ""
+}
+
+// line returns the entry on one line: its date, the created commit or "draft", and the header.
+func (e historyEntry) line() string {
+	origin := "draft"
+	if !e.draft() {
+		origin = e.Hash[:min(len(e.Hash), 7)]
+	}
+	return fmt.Sprintf("%s  %-7s  %s", e.Time.Local().Format("2006-01-02 15:04"), origin, e.Message.header())
+}
+
+// writeFileAtomic writes the data to a temporary file next to the file, then renames it over the
+// file, so that an interrupted write keeps the previous content. The temporary file has a unique
+// name, so that concurrent writers do not clobber each other's.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // nolint:errcheck
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// dataDir returns the directory holding the git-cm data of the repository, .git/git-cm.
+// It is shared by the worktrees of the repository.
+func dataDir(repo *git.Repository) (string, error) {
+	dir, err := commonDir(repo)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "git-cm"), nil
+}
+
+// loadHistory returns the saved messages, the most recent first.
+// A missing history is empty.
+func loadHistory(repo *git.Repository) ([]historyEntry, error) {
+	dir, err := dataDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, historyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Message == nil {
+			// Skip the entries that cannot be read, e.g. a line cut by a crash.
+			continue
+		}
+		entries = append([]historyEntry{e}, entries...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// saveHistory adds a message to the history. A draft identical to the most recent message
+// is not saved again, and a committed message replaces its identical draft.
+func saveHistory(repo *git.Repository, e historyEntry) error {
+	entries, err := loadHistory(repo)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 && entries[0].Message.String() == e.Message.String() {
+		if e.draft() {
+			return nil
+		}
+		if entries[0].draft() {
+			entries = entries[1:]
+		}
+	}
+	entries = append([]historyEntry{e}, entries...)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[:maxHistoryEntries]
+	}
+
+	var b bytes.Buffer
+	for i := len(entries) - 1; i >= 0; i-- {
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode history: %w", err)
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	dir, err := dataDir(repo)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, historyFile), b.Bytes()); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// recordMessage saves the message in the history, only warning on failure since the history
+// is a convenience. Empty messages are not saved.
+func recordMessage(repo *git.Repository, msg *commitMessage, hash string) {
+	if msg == nil || msg.empty() {
+		return
+	}
+	if err := saveHistory(repo, historyEntry{Time: time.Now(), Hash: hash, Message: msg}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save the message history: %v\n", err)
+	}
+}
+
+// reuseFlag is the value of --reuse, which takes an optional 1-based position in the history:
+// --reuse is the most recent message and --reuse=2 the one before.
+type reuseFlag struct {
+	n *int
+}
+
+// String returns the position, or an empty string when --reuse is not given.
+func (f reuseFlag) String() string {
+	if f.n == nil || *f.n == 0 {
+		return ""
+	}
+	return strconv.Itoa(*f.n)
+}
+
+// Set parses the position given with --reuse=N; "true" is set by a bare --reuse.
+func (f reuseFlag) Set(s string) error {
+	if s == "true" {
+		*f.n = 1
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return fmt.Errorf("must be a positive number")
+	}
+	*f.n = n
+	return nil
+}
+
+// IsBoolFlag lets --reuse be given without a value.
+func (f reuseFlag) IsBoolFlag() bool {
+	return true
+}
+
+// reusedMessage returns the n-th most recent message of the history, starting from 1.
+func reusedMessage(repo *git.Repository, n int) (*commitMessage, error) {
+	entries, err := loadHistory(repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no message to reuse: the history is empty")
+	}
+	if n > len(entries) {
+		return nil, fmt.Errorf("no message to reuse: the history has %d messages", len(entries))
+	}
+	return entries[n-1].Message, nil
+}
+
+// newLogFlagSet returns the flag set of the log command, storing the number of messages to show in limit.
+func newLogFlagSet(limit *int) *flag.FlagSet {
+	fs := newFlagSet("log")
+	fs.IntVar(limit, "n", 10, "Show the `N` most recent messages (0 shows all)")
+	return fs
+}
+
+// runLog implements `git cm log`, which lists the saved messages from the most recent,
+// numbered like --reuse=N.
+func runLog(args []string) int {
+	var limit int
+	fs := newLogFlagSet(&limit)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return exitWithError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+	if limit < 0 {
+		return exitWithError(fmt.Errorf("-n must not be negative"))
+	}
+
+	loc, err := findRepo()
+	if err != nil {
+		return exitWithError(err)
+	}
+	repo, err := openRepo(loc)
+	if err != nil {
+		return exitWithError(err)
+	}
+
+	entries, err := loadHistory(repo)
+	if err != nil {
+		return exitWithError(err)
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	if err := writeHistory(os.Stdout, entries); err != nil {
+		return exitWithError(err)
+	}
+	return exitOK
+}
+
+// writeHistory writes one line per entry, numbered from 1.
+func writeHistory(w io.Writer, entries []historyEntry) error {
+	width := len(strconv.Itoa(len(entries)))
+	for i, e := range entries {
+		if _, err := fmt.Fprintf(w, "%*d  %s\n", width, i+1, e.line()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
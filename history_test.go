@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.json")
+
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("expected %q, got %q", content, data)
+		}
+	}
+
+	// The temporary files are renamed or removed.
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only main.json, got %d files", len(files))
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "main.json"), nil); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestSaveHistory(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	entries, err := loadHistory(repo)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty history, got %v, %v", entries, err)
+	}
+
+	draft := &commitMessage{Prefix: "fix", Summary: "handle nil"}
+	for _, e := range []historyEntry{
+		{Message: &commitMessage{Prefix: "feat", Summary: "add history"}, Hash: "1234567890"},
+		{Message: draft},
+		// The same draft is saved once, and replaced when it is committed.
+		{Message: draft},
+		{Message: draft, Hash: "abcdef1234"},
+	} {
+		if err := saveHistory(repo, e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err = loadHistory(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Hash+" "+e.Message.header())
+	}
+	expected := []string{"abcdef1234 fix: handle nil", "1234567890 feat: add history"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestSaveHistory_Limit(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	for i := range maxHistoryEntries + 5 {
+		msg := &commitMessage{Prefix: "feat", Summary: fmt.Sprintf("change %d", i)}
+		if err := saveHistory(repo, historyEntry{Time: time.Now(), Message: msg}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	entries, err := loadHistory(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != maxHistoryEntries {
+		t.Errorf("expected %d entries, got %d", maxHistoryEntries, len(entries))
+	}
+	if expected := fmt.Sprintf("change %d", maxHistoryEntries+4); entries[0].Message.Summary != expected {
+		t.Errorf("expected the most recent entry %q, got %q", expected, entries[0].Message.Summary)
+	}
+}
+
+func TestLoadHistory_SkipsBrokenLines(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	dir := filepath.Join(root, ".git", "git-cm")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create %s: %v", dir, err)
+	}
+	content := `{"time":"2025-01-02T03:04:05Z","message":{"prefix":"feat","summary":"kept"}}` + "\n" + `{"time":"2025-01-02T03:`
+	if err := os.WriteFile(filepath.Join(dir, historyFile), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write history: %v", err)
+	}
+
+	entries, err := loadHistory(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message.Summary != "kept" {
+		t.Errorf("expected the readable entry only, got %+v", entries)
+	}
+}
+
+func TestReusedMessage(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if _, err := reusedMessage(repo, 1); err == nil {
+		t.Error("expected error for an empty history, but got nil")
+	}
+
+	for _, summary := range []string{"first", "second"} {
+		if err := saveHistory(repo, historyEntry{Message: &commitMessage{Prefix: "feat", Summary: summary}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		n         int
+		expected  string
+		expectErr bool
+	}{
+		{n: 1, expected: "second"},
+		{n: 2, expected: "first"},
+		{n: 3, expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			msg, err := reusedMessage(repo, tt.n)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.Summary != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, msg.Summary)
+			}
+		})
+	}
+}
+
+func TestWriteHistory(t *testing.T) {
+	when := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	entries := []historyEntry{
+		{Time: when, Message: &commitMessage{Prefix: "fix", Summary: "handle nil"}},
+		{Time: when, Hash: "1234567890abcdef", Message: &commitMessage{Prefix: "feat", Scope: "ui", Summary: "add history"}},
+	}
+
+	var b strings.Builder
+	if err := writeHistory(&b, entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "1  2024-05-01 09:30  draft    fix: handle nil\n" +
+		"2  2024-05-01 09:30  1234567  feat(ui): add history\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
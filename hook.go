@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// hookMarker identifies hook scripts written by git-cm.
+const hookMarker = "# Installed by git-cm."
+
+// chainedHookSuffix is appended to the name of a hook that existed before git-cm installed its own.
+// The git-cm hook runs it first, so existing hooks keep working.
+const chainedHookSuffix = ".git-cm-orig"
+
+// managedHooks lists the hooks git-cm can install.
+var managedHooks = []string{"prepare-commit-msg", "commit-msg"}
+
+// hookCommands holds the git-cm invocation of each managed hook.
+var hookCommands = map[string]string{
+	// Compose the message in the TUI for a plain `git commit` only: the second argument
+	// is set for -m, -F, templates, merges, squashes and amends. The TUI needs the terminal,
+	// which git does not pass to hooks.
+	"prepare-commit-msg": `if [ -z "$2" ] && (exec < /dev/tty) 2>/dev/null; then
+	exec git cm --message-file "$1" < /dev/tty > /dev/tty
+fi`,
+	"commit-msg": `exec git cm lint --file "$1"`,
+}
+
+// hookScript returns the shell script installed for the named hook.
+func hookScript(name string) string {
+	return fmt.Sprintf(`#!/bin/sh
+%s Remove with `+"`git cm hook uninstall`"+`.
+chained="$(dirname "$0")/%s%s"
+if [ -x "$chained" ]; then
+	"$chained" "$@" || exit $?
+fi
+%s
+`, hookMarker, name, chainedHookSuffix, hookCommands[name])
+}
+
+// hooksDir returns the directory git runs hooks from: core.hooksPath when it is set
+// (relative paths are resolved against the repository root), or .git/hooks otherwise.
+func hooksDir(repo *git.Repository, root string) (string, error) {
+	p, err := gitConfigValue(repo, "core", "", "hooksPath")
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		dir, err := commonDir(repo)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "hooks"), nil
+	}
+
+	p, err = expandHomeDir(p)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(root, p)
+	}
+	return p, nil
+}
+
+// hookError is returned when a git hook exits with a non-zero status.
+// Output holds what the hook printed, so that it can be shown to the user.
+type hookError struct {
+	Hook   string
+	Output string
+	Err    error
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %v", e.Hook, e.Err)
+}
+
+func (e *hookError) Unwrap() error {
+	return e.Err
+}
+
+// hookRunner runs the repository's git hooks the way `git commit` does.
+type hookRunner struct {
+	dir    string    // directory holding the hooks
+	root   string    // worktree root, the working directory of the hooks
+	gitDir string    // git directory holding the index and COMMIT_EDITMSG
+	output io.Writer // receives the output of hooks that succeed
+}
+
+// newHookRunner returns a hookRunner for the repository whose worktree is at root.
+func newHookRunner(repo *git.Repository, root string) (*hookRunner, error) {
+	dir, err := hooksDir(repo, root)
+	if err != nil {
+		return nil, err
+	}
+
+	gd, err := gitDir(repo)
+	if err != nil {
+		return nil, err
+	}
+	return &hookRunner{dir: dir, root: root, gitDir: gd, output: os.Stderr}, nil
+}
+
+// exists reports whether the named hook is installed and executable.
+func (h *hookRunner) exists(name string) bool {
+	info, err := os.Stat(filepath.Join(h.dir, name))
+	if err != nil || info.IsDir() {
+		return false
+	}
+	// Windows has no executable bit; git runs every hook there.
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
+
+// run runs the named hook with args, if it exists. Like git, it runs the hook from the worktree
+// root with GIT_INDEX_FILE set and no standard input. The output of a failing hook is carried by
+// the returned *hookError; the output of a successful one is copied to h.output.
+func (h *hookRunner) run(name string, args ...string) error {
+	return h.runInput(name, "", args...)
+}
+
+// runInput is like run, but passes input on the standard input of the hook.
+func (h *hookRunner) runInput(name, input string, args ...string) error {
+	if !h.exists(name) {
+		return nil
+	}
+
+	p := filepath.Join(h.dir, name)
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("sh", append([]string{p}, args...)...)
+	} else {
+		cmd = exec.Command(p, args...)
+	}
+	cmd.Dir = h.root
+	cmd.Env = append(os.Environ(),
+		"GIT_INDEX_FILE="+filepath.Join(h.gitDir, "index"),
+		"GIT_EDITOR=:",
+	)
+
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return &hookError{Hook: name, Output: out.String(), Err: err}
+	}
+
+	_, _ = h.output.Write(out.Bytes())
+	return nil
+}
+
+// runCommitMsg writes msg to .git/COMMIT_EDITMSG, runs the commit-msg hook on it and returns
+// the message as left in the file by the hook. A rewritten message is cleaned up like git does.
+func (h *hookRunner) runCommitMsg(msg string) (string, error) {
+	p := filepath.Join(h.gitDir, "COMMIT_EDITMSG")
+	if err := os.WriteFile(p, []byte(msg+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", p, err)
+	}
+
+	if err := h.run("commit-msg", p); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	if string(data) == msg+"\n" {
+		return msg, nil
+	}
+	return cleanupMessage(string(data)), nil
+}
+
+// isGitCMHook reports whether the file at path is a hook script written by git-cm.
+func isGitCMHook(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), hookMarker)
+}
+
+// installHook writes the git-cm script for the named hook into dir. An existing hook that
+// was not written by git-cm is renamed with chainedHookSuffix and run by the new script.
+func installHook(dir, name string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	p := filepath.Join(dir, name)
+	if _, err := os.Stat(p); err == nil && !isGitCMHook(p) {
+		chained := p + chainedHookSuffix
+		if _, err := os.Stat(chained); err == nil {
+			return fmt.Errorf("cannot chain the existing %s hook: %s already exists", name, chained)
+		}
+		if err := os.Rename(p, chained); err != nil {
+			return fmt.Errorf("failed to move the existing %s hook: %w", name, err)
+		}
+	}
+
+	if err := os.WriteFile(p, []byte(hookScript(name)), 0755); err != nil {
+		return fmt.Errorf("failed to write %s hook: %w", name, err)
+	}
+	// WriteFile does not change the mode of an existing file.
+	if err := os.Chmod(p, 0755); err != nil {
+		return fmt.Errorf("failed to make %s hook executable: %w", name, err)
+	}
+	return nil
+}
+
+// uninstallHook removes the git-cm script for the named hook from dir and restores the hook
+// it was chained to. It returns false if the hook in dir was not written by git-cm.
+func uninstallHook(dir, name string) (bool, error) {
+	p := filepath.Join(dir, name)
+	if !isGitCMHook(p) {
+		return false, nil
+	}
+
+	if err := os.Remove(p); err != nil {
+		return false, fmt.Errorf("failed to remove %s hook: %w", name, err)
+	}
+
+	chained := p + chainedHookSuffix
+	if _, err := os.Stat(chained); err == nil {
+		if err := os.Rename(chained, p); err != nil {
+			return false, fmt.Errorf("failed to restore the previous %s hook: %w", name, err)
+		}
+	}
+	return true, nil
+}
+
+// runHookInstaller implements `git cm hook install|uninstall [HOOK...]`, which manages the
+// prepare-commit-msg hook (compose messages in the TUI on `git commit`) and the commit-msg hook
+// (lint messages). Both hooks are managed when none is given.
+func runHookInstaller(args []string) int {
+	fs := newFlagSet("hook")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() == 0 || (fs.Arg(0) != "install" && fs.Arg(0) != "uninstall") {
+		fs.Usage()
+		return exitError
+	}
+	action := fs.Arg(0)
+
+	hooks := fs.Args()[1:]
+	if len(hooks) == 0 {
+		hooks = managedHooks
+	}
+	for _, name := range hooks {
+		if !slices.Contains(managedHooks, name) {
+			return exitWithError(fmt.Errorf("unsupported hook %q", name))
+		}
+	}
+
+	loc, err := findRepo()
+	if err != nil {
+		return exitWithError(err)
+	}
+
+	repo, err := openRepo(loc)
+	if err != nil {
+		return exitWithError(err)
+	}
+
+	dir, err := hooksDir(repo, loc.Root)
+	if err != nil {
+		return exitWithError(err)
+	}
+
+	for _, name := range hooks {
+		p := filepath.Join(dir, name)
+		if action == "install" {
+			if err := installHook(dir, name); err != nil {
+				return exitWithError(err)
+			}
+			fmt.Printf("Installed %s\n", p)
+			continue
+		}
+
+		removed, err := uninstallHook(dir, name)
+		if err != nil {
+			return exitWithError(err)
+		}
+		if removed {
+			fmt.Printf("Removed %s\n", p)
+		} else {
+			fmt.Printf("Skipped %s: not installed by git-cm\n", p)
+		}
+	}
+	return exitOK
+}
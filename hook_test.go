@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestHooksDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		hooksPath string
+		expected  func(root string) string
+	}{
+		{
+			name:     "Default",
+			expected: func(root string) string { return filepath.Join(root, ".git", "hooks") },
+		},
+		{
+			name:      "RelativeHooksPath",
+			hooksPath: ".githooks",
+			expected:  func(root string) string { return filepath.Join(root, ".githooks") },
+		},
+		{
+			name:      "AbsoluteHooksPath",
+			hooksPath: "/opt/hooks",
+			expected:  func(string) string { return "/opt/hooks" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", "")
+
+			root := t.TempDir()
+			repo, err := git.PlainInit(root, false)
+			if err != nil {
+				t.Fatalf("failed to init repo: %v", err)
+			}
+
+			if tt.hooksPath != "" {
+				cfg, err := repo.Config()
+				if err != nil {
+					t.Fatalf("failed to get repository config: %v", err)
+				}
+				cfg.Raw.Section("core").SetOption("hooksPath", tt.hooksPath)
+				if err := repo.Storer.SetConfig(cfg); err != nil {
+					t.Fatalf("failed to set repository config: %v", err)
+				}
+			}
+
+			dir, err := hooksDir(repo, root)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dir != tt.expected(root) {
+				t.Errorf("expected %q, got %q", tt.expected(root), dir)
+			}
+		})
+	}
+}
+
+func TestInstallAndUninstallHook(t *testing.T) {
+	t.Run("FreshInstall", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "hooks")
+		if err := installHook(dir, "commit-msg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		p := filepath.Join(dir, "commit-msg")
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected hook to be written: %v", err)
+		}
+		if info.Mode()&0111 == 0 {
+			t.Error("expected hook to be executable")
+		}
+		data, _ := os.ReadFile(p)
+		if !strings.Contains(string(data), "git cm lint --file") {
+			t.Errorf("unexpected hook content:\n%s", data)
+		}
+
+		// Installing twice overwrites the git-cm hook without chaining it to itself.
+		if err := installHook(dir, "commit-msg"); err != nil {
+			t.Fatalf("unexpected error on reinstall: %v", err)
+		}
+		if _, err := os.Stat(p + chainedHookSuffix); err == nil {
+			t.Error("expected the git-cm hook not to be chained to itself")
+		}
+
+		removed, err := uninstallHook(dir, "commit-msg")
+		if err != nil || !removed {
+			t.Fatalf("expected hook to be removed, got removed=%v err=%v", removed, err)
+		}
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Error("expected hook file to be deleted")
+		}
+	})
+
+	t.Run("ChainExistingHook", func(t *testing.T) {
+		dir := t.TempDir()
+		p := filepath.Join(dir, "prepare-commit-msg")
+		original := "#!/bin/sh\necho existing\n"
+		if err := os.WriteFile(p, []byte(original), 0755); err != nil {
+			t.Fatalf("failed to write existing hook: %v", err)
+		}
+
+		if err := installHook(dir, "prepare-commit-msg"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		chained, err := os.ReadFile(p + chainedHookSuffix)
+		if err != nil || string(chained) != original {
+			t.Fatalf("expected the existing hook to be chained, got %q (%v)", chained, err)
+		}
+
+		removed, err := uninstallHook(dir, "prepare-commit-msg")
+		if err != nil || !removed {
+			t.Fatalf("expected hook to be removed, got removed=%v err=%v", removed, err)
+		}
+		restored, err := os.ReadFile(p)
+		if err != nil || string(restored) != original {
+			t.Errorf("expected the existing hook to be restored, got %q (%v)", restored, err)
+		}
+	})
+
+	t.Run("UninstallForeignHook", func(t *testing.T) {
+		dir := t.TempDir()
+		p := filepath.Join(dir, "commit-msg")
+		if err := os.WriteFile(p, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("failed to write existing hook: %v", err)
+		}
+
+		removed, err := uninstallHook(dir, "commit-msg")
+		if err != nil || removed {
+			t.Errorf("expected foreign hook to be left alone, got removed=%v err=%v", removed, err)
+		}
+		if _, err := os.Stat(p); err != nil {
+			t.Error("expected foreign hook to be kept")
+		}
+	})
+}
+
+// writeTestHook writes an executable hook script into the hooks directory of the repository at root.
+func writeTestHook(t *testing.T, root, name, script string) {
+	t.Helper()
+	dir := filepath.Join(root, ".git", "hooks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create hooks directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write %s hook: %v", name, err)
+	}
+}
+
+func TestCommitRepo_Hooks(t *testing.T) {
+	isolateGitConfig(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("hook scripts need a POSIX shell")
+	}
+
+	tests := []struct {
+		name            string
+		hooks           map[string]string
+		noVerify        bool
+		expectHook      string
+		expectOutput    string
+		expectedMessage string
+	}{
+		{
+			name:            "NoHooks",
+			expectedMessage: "feat: add hooks",
+		},
+		{
+			name:         "PreCommitFails",
+			hooks:        map[string]string{"pre-commit": "echo 'gofmt needed'\nexit 1"},
+			expectHook:   "pre-commit",
+			expectOutput: "gofmt needed\n",
+		},
+		{
+			name:         "CommitMsgFails",
+			hooks:        map[string]string{"commit-msg": "grep -q '^Refs:' \"$1\" || { echo 'missing Refs' >&2; exit 1; }"},
+			expectHook:   "commit-msg",
+			expectOutput: "missing Refs\n",
+		},
+		{
+			name:            "CommitMsgRewrites",
+			hooks:           map[string]string{"commit-msg": "printf '\\nRefs: #42\\n# comment\\n' >> \"$1\""},
+			expectedMessage: "feat: add hooks\n\nRefs: #42",
+		},
+		{
+			name:            "NoVerify",
+			hooks:           map[string]string{"pre-commit": "exit 1", "commit-msg": "exit 1"},
+			noVerify:        true,
+			expectedMessage: "feat: add hooks",
+		},
+		{
+			name:            "PostCommitFailureIgnored",
+			hooks:           map[string]string{"post-commit": "exit 1"},
+			expectedMessage: "feat: add hooks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			repo, err := git.PlainInit(root, false)
+			if err != nil {
+				t.Fatalf("failed to init repo: %v", err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "file.txt"), []byte("content"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			wt, err := repo.Worktree()
+			if err != nil {
+				t.Fatalf("failed to get worktree: %v", err)
+			}
+			if _, err := wt.Add("file.txt"); err != nil {
+				t.Fatalf("failed to stage file: %v", err)
+			}
+			for name, script := range tt.hooks {
+				writeTestHook(t, root, name, script)
+			}
+
+			msg := &commitMessage{Prefix: "feat", Summary: "add hooks"}
+			hash, err := commitRepo(repo, author{Name: "Tester", Email: "tester@example.com"}, msg, commitOptions{NoVerify: tt.noVerify})
+			if tt.expectHook != "" {
+				var he *hookError
+				if !errors.As(err, &he) {
+					t.Fatalf("expected hook error, got %v", err)
+				}
+				if !errors.Is(err, errCommitFailed) {
+					t.Errorf("expected errCommitFailed, got %v", err)
+				}
+				if he.Hook != tt.expectHook {
+					t.Errorf("expected %s hook to fail, got %s", tt.expectHook, he.Hook)
+				}
+				if he.Output != tt.expectOutput {
+					t.Errorf("expected output %q, got %q", tt.expectOutput, he.Output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c, err := repo.CommitObject(plumbing.NewHash(hash))
+			if err != nil {
+				t.Fatalf("failed to get commit: %v", err)
+			}
+			if c.Message != tt.expectedMessage {
+				t.Errorf("expected message %q, got %q", tt.expectedMessage, c.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContextLines is the number of unchanged lines shown around the changes of a hunk,
+// like the default of `git diff -U`.
+const diffContextLines = 3
+
+// diffLine is a line of a diff. Op is ' ' for an unchanged line, '-' for a removed line and
+// '+' for an added line. Text keeps the line ending, which is missing on the last line of a
+// file without a final newline.
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+// changed reports whether the line is added or removed.
+func (l diffLine) changed() bool {
+	return l.Op != ' '
+}
+
+// hunk is a group of nearby changes with their context lines: the lines [Start, End) of a fileDiff.
+// OldStart and NewStart are the 1-based line numbers of its first line in both versions.
+type hunk struct {
+	Start, End         int
+	OldStart, NewStart int
+	OldLines, NewLines int
+}
+
+// header returns the "@@ -l,s +l,s @@" header of the hunk.
+func (h hunk) header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// fileDiff is the line diff between two versions of a file, split into hunks.
+// Lines holds every line of both versions so that any selection of changes can be applied.
+type fileDiff struct {
+	Lines []diffLine
+	Hunks []hunk
+}
+
+// diffFile computes the diff from the old to the new content of a file.
+// It returns an error for binary content, which cannot be staged line by line.
+func diffFile(old, new []byte) (*fileDiff, error) {
+	if isBinary(old) || isBinary(new) {
+		return nil, fmt.Errorf("binary files cannot be staged by hunk")
+	}
+
+	d := &fileDiff{}
+	for _, c := range diff.Do(string(old), string(new)) {
+		op := byte(' ')
+		switch c.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, text := range strings.SplitAfter(c.Text, "\n") {
+			if text != "" {
+				d.Lines = append(d.Lines, diffLine{Op: op, Text: text})
+			}
+		}
+	}
+	d.Hunks = splitHunks(d.Lines)
+	return d, nil
+}
+
+// splitHunks groups the changed lines into hunks with diffContextLines lines of context,
+// merging changes whose context would overlap.
+func splitHunks(lines []diffLine) []hunk {
+	var hunks []hunk
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(lines); {
+		if !lines[i].changed() {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// Start the hunk with the preceding context lines.
+		start := max(i-diffContextLines, 0)
+		h := hunk{Start: start, OldStart: oldLine - (i - start), NewStart: newLine - (i - start)}
+
+		// Extend it while the next change is close enough for the contexts to touch.
+		end, unchanged := i, 0
+		for j := i; j < len(lines) && unchanged <= 2*diffContextLines; j++ {
+			if lines[j].changed() {
+				end, unchanged = j+1, 0
+			} else {
+				unchanged++
+			}
+		}
+		h.End = min(end+diffContextLines, len(lines))
+
+		for _, l := range lines[h.Start:h.End] {
+			if l.Op != '+' {
+				h.OldLines++
+			}
+			if l.Op != '-' {
+				h.NewLines++
+			}
+		}
+		// Like git, an empty side of a hunk starts at the line before it.
+		if h.OldLines == 0 {
+			h.OldStart--
+		}
+		if h.NewLines == 0 {
+			h.NewStart--
+		}
+		hunks = append(hunks, h)
+
+		for _, l := range lines[i:h.End] {
+			if l.Op != '+' {
+				oldLine++
+			}
+			if l.Op != '-' {
+				newLine++
+			}
+		}
+		i = h.End
+	}
+	return hunks
+}
+
+// apply returns the old content with the selected changes applied: selected added lines are
+// inserted and selected removed lines are dropped, while the other changes are left out.
+func (d *fileDiff) apply(selected map[int]bool) []byte {
+	var b bytes.Buffer
+	for i, l := range d.Lines {
+		switch {
+		case !l.changed(),
+			l.Op == '-' && !selected[i],
+			l.Op == '+' && selected[i]:
+			// Only the last line of the old file may lack its newline. A line kept after it
+			// needs one, like git pairs it with its replacement ("\ No newline at end of file").
+			if n := b.Len(); n > 0 && b.Bytes()[n-1] != '\n' {
+				b.WriteByte('\n')
+			}
+			b.WriteString(l.Text)
+		}
+	}
+	return b.Bytes()
+}
+
+// hunkAt returns the index of the hunk containing the given line, or -1.
+func (d *fileDiff) hunkAt(line int) int {
+	for i, h := range d.Hunks {
+		if line >= h.Start && line < h.End {
+			return i
+		}
+	}
+	return -1
+}
+
+// diffStats summarizes a set of file diffs like `git diff --shortstat`.
+type diffStats struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+// add counts a changed file and its added and removed lines; d is nil for a binary file.
+func (s *diffStats) add(d *fileDiff) {
+	s.Files++
+	if d == nil {
+		return
+	}
+	for _, l := range d.Lines {
+		switch l.Op {
+		case '+':
+			s.Insertions++
+		case '-':
+			s.Deletions++
+		}
+	}
+}
+
+// String returns the stats in git's format, e.g. "2 files changed, 3 insertions(+), 1 deletion(-)".
+func (s diffStats) String() string {
+	str := plural(s.Files, "file") + " changed"
+	if s.Insertions > 0 {
+		str += ", " + plural(s.Insertions, "insertion") + "(+)"
+	}
+	if s.Deletions > 0 {
+		str += ", " + plural(s.Deletions, "deletion") + "(-)"
+	}
+	return str
+}
+
+// plural returns the count followed by the noun, in the plural form unless the count is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// isBinary reports whether the content looks binary, using git's heuristic of a NUL byte
+// in the first 8000 bytes.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns the lines "1\n" to "n\n", with the given lines replaced.
+func numberedLines(n int, replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if r, ok := replace[i]; ok {
+			b.WriteString(r)
+			continue
+		}
+		fmt.Fprintf(&b, "%d\n", i)
+	}
+	return b.String()
+}
+
+func TestDiffFile_Hunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		expected []string
+	}{
+		{
+			name:     "SingleChange",
+			old:      numberedLines(10, nil),
+			new:      numberedLines(10, map[int]string{5: "five\n"}),
+			expected: []string{"@@ -2,7 +2,7 @@"},
+		},
+		{
+			name:     "DistantChanges",
+			old:      numberedLines(20, nil),
+			new:      numberedLines(20, map[int]string{2: "two\n", 18: "eighteen\n"}),
+			expected: []string{"@@ -1,5 +1,5 @@", "@@ -15,6 +15,6 @@"},
+		},
+		{
+			name:     "NearbyChangesMerged",
+			old:      numberedLines(20, nil),
+			new:      numberedLines(20, map[int]string{5: "five\n", 11: "eleven\n"}),
+			expected: []string{"@@ -2,13 +2,13 @@"},
+		},
+		{
+			name:     "NewFile",
+			old:      "",
+			new:      "a\nb\n",
+			expected: []string{"@@ -0,0 +1,2 @@"},
+		},
+		{
+			name:     "NoChanges",
+			old:      "a\n",
+			new:      "a\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := diffFile([]byte(tt.old), []byte(tt.new))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var headers []string
+			for _, h := range d.Hunks {
+				headers = append(headers, h.header())
+			}
+			if strings.Join(headers, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("expected hunks %q, got %q", tt.expected, headers)
+			}
+		})
+	}
+}
+
+func TestDiffFile_Binary(t *testing.T) {
+	if _, err := diffFile([]byte("a\n"), []byte("a\x00b")); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestFileDiff_Apply(t *testing.T) {
+	old := "a\nb\nc\n"
+	new := "a\nB\nc\nd"
+	d, err := diffFile([]byte(old), []byte(new))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Index the changed lines by their text, e.g. "-b" and "+B".
+	lines := map[string]int{}
+	for i, l := range d.Lines {
+		lines[string(l.Op)+strings.TrimSuffix(l.Text, "\n")] = i
+	}
+
+	tests := []struct {
+		name     string
+		selected []string
+		expected string
+	}{
+		{name: "Nothing", selected: nil, expected: old},
+		{name: "Everything", selected: []string{"-b", "+B", "+d"}, expected: new},
+		{name: "RemovalOnly", selected: []string{"-b"}, expected: "a\nc\n"},
+		{name: "AdditionOnly", selected: []string{"+B"}, expected: "a\nb\nB\nc\n"},
+		{name: "LastLineWithoutNewline", selected: []string{"+d"}, expected: "a\nb\nc\nd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := map[int]bool{}
+			for _, s := range tt.selected {
+				i, ok := lines[s]
+				if !ok {
+					t.Fatalf("line %q not found in %+v", s, d.Lines)
+				}
+				selected[i] = true
+			}
+			if got := string(d.apply(selected)); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFileDiff_ApplyNoNewlineAtEOF(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		selected []string
+		expected string
+	}{
+		{name: "Nothing", old: "a", new: "a\nb", selected: nil, expected: "a"},
+		{name: "Everything", old: "a", new: "a\nb", selected: []string{"-a", "+a", "+b"}, expected: "a\nb"},
+		{name: "AdditionOnly", old: "a", new: "a\nb", selected: []string{"+b"}, expected: "a\nb"},
+		{name: "ReplacedLastLine", old: "a", new: "b\nc", selected: []string{"+c"}, expected: "a\nc"},
+		{name: "RemovalOnly", old: "a", new: "b\nc", selected: []string{"-a"}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := diffFile([]byte(tt.old), []byte(tt.new))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			selected := map[int]bool{}
+			for _, s := range tt.selected {
+				i := slices.IndexFunc(d.Lines, func(l diffLine) bool {
+					return string(l.Op)+strings.TrimSuffix(l.Text, "\n") == s
+				})
+				if i < 0 {
+					t.Fatalf("line %q not found in %+v", s, d.Lines)
+				}
+				selected[i] = true
+			}
+			if got := string(d.apply(selected)); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDiffStats_String(t *testing.T) {
+	tests := []struct {
+		stats    diffStats
+		expected string
+	}{
+		{stats: diffStats{Files: 1, Insertions: 1, Deletions: 1}, expected: "1 file changed, 1 insertion(+), 1 deletion(-)"},
+		{stats: diffStats{Files: 2, Insertions: 3}, expected: "2 files changed, 3 insertions(+)"},
+		{stats: diffStats{Files: 1, Deletions: 2}, expected: "1 file changed, 2 deletions(-)"},
+		{stats: diffStats{Files: 1}, expected: "1 file changed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := tt.stats.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitDateLayouts lists the date formats accepted by --date and GIT_AUTHOR_DATE, besides git's
+// internal "<unix timestamp> <offset>" format.
+var gitDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon Jan 2 15:04:05 2006 -0700",
+}
+
+// parseIdent parses an identity in the "Name <email>" form.
+func parseIdent(s string) (author, error) {
+	s = strings.TrimSpace(s)
+	open := strings.LastIndex(s, "<")
+	if open < 0 || !strings.HasSuffix(s, ">") {
+		return author{}, fmt.Errorf("identity %q is not in the \"Name <email>\" form", s)
+	}
+
+	a := author{
+		Name:  strings.TrimSpace(s[:open]),
+		Email: strings.TrimSpace(s[open+1 : len(s)-1]),
+	}
+	if a.Name == "" || a.Email == "" {
+		return author{}, fmt.Errorf("identity %q needs both a name and an email", s)
+	}
+	return a, nil
+}
+
+// findAuthor resolves the --author option like git does: an identity in the "Name <email>" form
+// is used as is, anything else is matched case-insensitively against the past commit authors
+// ("Name <email>", most recent first).
+func findAuthor(pattern string, authors []string) (author, error) {
+	if strings.Contains(pattern, "<") {
+		return parseIdent(pattern)
+	}
+
+	p := strings.ToLower(pattern)
+	for _, a := range authors {
+		if strings.Contains(strings.ToLower(a), p) {
+			return parseIdent(a)
+		}
+	}
+	return author{}, fmt.Errorf("--author %q is not \"Name <email>\" and matches no existing author", pattern)
+}
+
+// parseGitDate parses a date given to --date or in GIT_AUTHOR_DATE / GIT_COMMITTER_DATE:
+// git's internal "[@]<unix timestamp> <offset>" format, ISO 8601 or RFC 2822.
+// Dates without a time zone are in the local time zone.
+func parseGitDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "now" {
+		return time.Now(), nil
+	}
+
+	if t, ok := parseRawGitDate(s); ok {
+		return t, nil
+	}
+	for _, layout := range gitDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseRawGitDate parses git's internal "[@]<unix timestamp> [<+|-hhmm>]" date format.
+func parseRawGitDate(s string) (time.Time, bool) {
+	ts, offset, hasOffset := strings.Cut(strings.TrimPrefix(s, "@"), " ")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t := time.Unix(sec, 0)
+	if !hasOffset {
+		return t.UTC(), true
+	}
+
+	zone, err := time.Parse("-0700", offset)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.In(zone.Location()), true
+}
+
+// envDate returns the date in the environment variable, or the zero time when it is not set.
+func envDate(name string) (time.Time, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := parseGitDate(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIdent(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  author
+		expectErr bool
+	}{
+		{input: "Alice <alice@example.com>", expected: author{Name: "Alice", Email: "alice@example.com"}},
+		{input: "  Bob Smith  < bob@example.com > ", expected: author{Name: "Bob Smith", Email: "bob@example.com"}},
+		{input: "Alice", expectErr: true},
+		{input: "<alice@example.com>", expectErr: true},
+		{input: "Alice <>", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			a, err := parseIdent(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, a)
+			}
+		})
+	}
+}
+
+func TestFindAuthor(t *testing.T) {
+	authors := []string{"Alice <alice@example.com>", "Bob <bob@example.org>"}
+
+	tests := []struct {
+		pattern   string
+		expected  author
+		expectErr bool
+	}{
+		{pattern: "Carol <carol@example.com>", expected: author{Name: "Carol", Email: "carol@example.com"}},
+		{pattern: "bob", expected: author{Name: "Bob", Email: "bob@example.org"}},
+		{pattern: "example", expected: author{Name: "Alice", Email: "alice@example.com"}},
+		{pattern: "carol", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			a, err := findAuthor(tt.pattern, authors)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if a != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, a)
+			}
+		})
+	}
+}
+
+func TestParseGitDate(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string // RFC 3339
+		expectErr bool
+	}{
+		{input: "@1700000000 +0900", expected: "2023-11-15T07:13:20+09:00"},
+		{input: "1700000000 -0130", expected: "2023-11-14T20:43:20-01:30"},
+		{input: "2024-02-29T12:34:56Z", expected: "2024-02-29T12:34:56Z"},
+		{input: "2024-02-29 12:34:56 +0200", expected: "2024-02-29T12:34:56+02:00"},
+		{input: "Thu, 29 Feb 2024 12:34:56 -0500", expected: "2024-02-29T12:34:56-05:00"},
+		{input: "yesterday", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := parseGitDate(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := d.Format(time.RFC3339); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ignoredMessagePattern matches headers generated by git or meant to be squashed later,
+// which are not linted.
+var ignoredMessagePattern = regexp.MustCompile(`^(Merge |Revert "|fixup! |squash! |amend! )`)
+
+// lintSeverity is the level at which a lint rule reports its violations.
+// Violations with severityError block the commit; warnings are only displayed.
+type lintSeverity string
+
+const (
+	severityOff     lintSeverity = "off"
+	severityWarning lintSeverity = "warning"
+	severityError   lintSeverity = "error"
+)
+
+// lintRuleConfig configures a lint rule in the [lint.<rule>] section of the git-cm config.
+// Zero values mean "not set" and fall back to the rule defaults.
+type lintRuleConfig struct {
+	Severity lintSeverity `toml:"severity" yaml:"severity"`
+	Value    int          `toml:"value" yaml:"value"`
+	Types    []string     `toml:"types" yaml:"types"`
+}
+
+// merge overrides the receiver with every field that is set in o.
+func (c *lintRuleConfig) merge(o lintRuleConfig) {
+	if o.Severity != "" {
+		c.Severity = o.Severity
+	}
+	if o.Value > 0 {
+		c.Value = o.Value
+	}
+	if len(o.Types) > 0 {
+		c.Types = o.Types
+	}
+}
+
+// lintViolation is a single problem reported by a lint rule.
+type lintViolation struct {
+	Rule     string       `json:"rule"`
+	Severity lintSeverity `json:"severity"`
+	Message  string       `json:"message"`
+}
+
+// String returns the violation in the "severity: message (rule)" form.
+func (v lintViolation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Severity, v.Message, v.Rule)
+}
+
+// lintRule is a check run against a commit message.
+// check returns a description of the problem, or an empty string when the message passes.
+type lintRule struct {
+	name     string
+	defaults lintRuleConfig
+	check    func(m *commitMessage, rc lintRuleConfig, cfg *settings) string
+}
+
+// lintRules lists the available rules in the order their violations are reported.
+var lintRules = []lintRule{
+	{
+		name:     "type-enum",
+		defaults: lintRuleConfig{Severity: severityError},
+		check: func(m *commitMessage, _ lintRuleConfig, cfg *settings) string {
+			if cfg.prefixIndex(m.Prefix) < 0 {
+				return fmt.Sprintf("type %q is not one of the configured prefixes", m.Prefix)
+			}
+			return ""
+		},
+	},
+	{
+		name:     "scope-enum",
+		defaults: lintRuleConfig{Severity: severityWarning},
+		check: func(m *commitMessage, _ lintRuleConfig, cfg *settings) string {
+			if m.Scope == "" || len(cfg.Scopes) == 0 || slices.Contains(cfg.Scopes, m.Scope) {
+				return ""
+			}
+			return fmt.Sprintf("scope %q is not one of the configured scopes", m.Scope)
+		},
+	},
+	{
+		name:     "summary-empty",
+		defaults: lintRuleConfig{Severity: severityError},
+		check: func(m *commitMessage, _ lintRuleConfig, _ *settings) string {
+			if strings.TrimSpace(m.Summary) == "" {
+				return "summary must not be empty"
+			}
+			return ""
+		},
+	},
+	{
+		name:     "header-max-length",
+		defaults: lintRuleConfig{Severity: severityError, Value: 100},
+		check: func(m *commitMessage, rc lintRuleConfig, _ *settings) string {
+			if n := len([]rune(m.header())); n > rc.Value {
+				return fmt.Sprintf("header is %d characters long, limit is %d", n, rc.Value)
+			}
+			return ""
+		},
+	},
+	{
+		name:     "summary-full-stop",
+		defaults: lintRuleConfig{Severity: severityError},
+		check: func(m *commitMessage, _ lintRuleConfig, _ *settings) string {
+			if strings.HasSuffix(strings.TrimSpace(m.Summary), ".") {
+				return "summary must not end with a period"
+			}
+			return ""
+		},
+	},
+	{
+		name:     "summary-case",
+		defaults: lintRuleConfig{Severity: severityWarning},
+		check: func(m *commitMessage, _ lintRuleConfig, _ *settings) string {
+			word := firstWord(m.Summary)
+			// Acronyms such as "API" are allowed.
+			if word == "" || strings.ToUpper(word) == word {
+				return ""
+			}
+			if unicode.IsUpper([]rune(word)[0]) {
+				return "summary must start with a lowercase letter"
+			}
+			return ""
+		},
+	},
+	{
+		name:     "summary-imperative",
+		defaults: lintRuleConfig{Severity: severityWarning},
+		check: func(m *commitMessage, _ lintRuleConfig, _ *settings) string {
+			word := strings.ToLower(firstWord(m.Summary))
+			if !looksNonImperative(word) {
+				return ""
+			}
+			return fmt.Sprintf("summary should use the imperative mood (%q is not imperative)", word)
+		},
+	},
+	{
+		name:     "body-max-line-length",
+		defaults: lintRuleConfig{Severity: severityWarning, Value: 100},
+		check: func(m *commitMessage, rc lintRuleConfig, _ *settings) string {
+			for i, line := range strings.Split(m.Description, "\n") {
+				if n := len([]rune(line)); n > rc.Value {
+					return fmt.Sprintf("body line %d is %d characters long, limit is %d", i+1, n, rc.Value)
+				}
+			}
+			return ""
+		},
+	},
+	{
+		name:     "body-required",
+		defaults: lintRuleConfig{Severity: severityError},
+		check: func(m *commitMessage, rc lintRuleConfig, _ *settings) string {
+			if slices.Contains(rc.Types, m.Prefix) && strings.TrimSpace(m.Description) == "" {
+				return fmt.Sprintf("a body is required for %q commits", m.Prefix)
+			}
+			return ""
+		},
+	},
+	{
+		name:     "trailer-format",
+		defaults: lintRuleConfig{Severity: severityError},
+		check: func(m *commitMessage, _ lintRuleConfig, _ *settings) string {
+			var checked commitMessage
+			for _, t := range m.Trailers {
+				if err := checked.addTrailer(t); err != nil {
+					return err.Error()
+				}
+			}
+			return ""
+		},
+	},
+}
+
+// firstWord returns the first whitespace-separated word of s.
+func firstWord(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// imperativeExceptions lists imperative verbs that end like past tense, gerund or third-person forms.
+var imperativeExceptions = []string{
+	"bring", "embed", "feed", "need", "proceed", "process", "seed", "shed", "speed", "string",
+	"access", "address", "bless", "bypass", "compress", "discuss", "focus", "pass", "press", "suppress",
+}
+
+// looksNonImperative is a heuristic that reports whether word looks like a past tense ("added"),
+// gerund ("adding") or third-person ("adds") form rather than an imperative verb.
+func looksNonImperative(word string) bool {
+	if len(word) < 4 || slices.Contains(imperativeExceptions, word) {
+		return false
+	}
+	switch {
+	case strings.HasSuffix(word, "ed"), strings.HasSuffix(word, "ing"):
+		return true
+	case strings.HasSuffix(word, "s"):
+		return !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is")
+	}
+	return false
+}
+
+// findLintRule returns the rule with the given name, or nil if it does not exist.
+func findLintRule(name string) *lintRule {
+	for i := range lintRules {
+		if lintRules[i].name == name {
+			return &lintRules[i]
+		}
+	}
+	return nil
+}
+
+// validateLintConfig checks that the [lint] section only refers to existing rules and severities.
+func validateLintConfig(lint map[string]lintRuleConfig) error {
+	for name, rc := range lint {
+		if findLintRule(name) == nil {
+			return fmt.Errorf("unknown lint rule %q", name)
+		}
+		switch rc.Severity {
+		case "", severityOff, severityWarning, severityError:
+		default:
+			return fmt.Errorf("invalid severity %q for lint rule %q", rc.Severity, name)
+		}
+	}
+	return nil
+}
+
+// lintMessage runs every enabled rule against m and returns the violations found.
+func lintMessage(m *commitMessage, cfg *settings) []lintViolation {
+	var violations []lintViolation
+	for _, rule := range lintRules {
+		rc := rule.defaults
+		rc.merge(cfg.Lint[rule.name])
+		if rc.Severity == severityOff {
+			continue
+		}
+
+		if problem := rule.check(m, rc, cfg); problem != "" {
+			violations = append(violations, lintViolation{Rule: rule.name, Severity: rc.Severity, Message: problem})
+		}
+	}
+	return violations
+}
+
+// lintRawMessage parses a raw commit message, as found in .git/COMMIT_EDITMSG or in a commit
+// object, and lints it. A header that cannot be parsed is reported as a single header-format
+// error. Merge, revert, fixup and squash messages are not linted.
+func lintRawMessage(raw string, cfg *settings) []lintViolation {
+	m, err := parseMessage(raw)
+	if errors.Is(err, errInvalidHeader) {
+		// None of the ignored headers parse as Conventional Commits, so they end up here.
+		if ignoredMessagePattern.MatchString(m.Summary) {
+			return nil
+		}
+		if strings.TrimSpace(m.Summary) == "" {
+			return []lintViolation{{Rule: "header-format", Severity: severityError, Message: "commit message must not be empty"}}
+		}
+		return []lintViolation{{Rule: "header-format", Severity: severityError, Message: err.Error()}}
+	}
+	return lintMessage(m, cfg)
+}
+
+// hasLintErrors reports whether any of the violations has the error severity.
+func hasLintErrors(violations []lintViolation) bool {
+	for _, v := range violations {
+		if v.Severity == severityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		msg           commitMessage
+		lint          map[string]lintRuleConfig
+		scopes        []string
+		expectedRules []string
+	}{
+		{
+			name:          "Clean",
+			msg:           commitMessage{Prefix: "feat", Summary: "add login form"},
+			expectedRules: nil,
+		},
+		{
+			name:          "EmptySummary",
+			msg:           commitMessage{Prefix: "feat"},
+			expectedRules: []string{"summary-empty"},
+		},
+		{
+			name:          "UnknownType",
+			msg:           commitMessage{Prefix: "wip", Summary: "add login form"},
+			expectedRules: []string{"type-enum"},
+		},
+		{
+			name:          "UnknownScope",
+			msg:           commitMessage{Prefix: "feat", Scope: "db", Summary: "add login form"},
+			scopes:        []string{"api", "ui"},
+			expectedRules: []string{"scope-enum"},
+		},
+		{
+			name:          "SummaryStyle",
+			msg:           commitMessage{Prefix: "feat", Summary: "Added login form."},
+			expectedRules: []string{"summary-full-stop", "summary-case", "summary-imperative"},
+		},
+		{
+			name:          "AcronymIsNotCaseViolation",
+			msg:           commitMessage{Prefix: "feat", Summary: "API client for billing"},
+			expectedRules: nil,
+		},
+		{
+			name:          "HeaderTooLong",
+			msg:           commitMessage{Prefix: "feat", Summary: strings.Repeat("a", 30)},
+			lint:          map[string]lintRuleConfig{"header-max-length": {Value: 20}},
+			expectedRules: []string{"header-max-length"},
+		},
+		{
+			name:          "BodyLineTooLong",
+			msg:           commitMessage{Prefix: "feat", Summary: "add login form", Description: "ok\n" + strings.Repeat("a", 101)},
+			expectedRules: []string{"body-max-line-length"},
+		},
+		{
+			name:          "BodyRequired",
+			msg:           commitMessage{Prefix: "fix", Summary: "handle nil"},
+			lint:          map[string]lintRuleConfig{"body-required": {Types: []string{"fix"}}},
+			expectedRules: []string{"body-required"},
+		},
+		{
+			name:          "RuleTurnedOff",
+			msg:           commitMessage{Prefix: "feat", Summary: "add login form."},
+			lint:          map[string]lintRuleConfig{"summary-full-stop": {Severity: severityOff}},
+			expectedRules: nil,
+		},
+		{
+			name: "MalformedTrailer",
+			msg: commitMessage{
+				Prefix:   "feat",
+				Summary:  "add login form",
+				Trailers: []trailer{{Key: "Bad key", Value: "x"}},
+			},
+			expectedRules: []string{"trailer-format"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultSettings()
+			cfg.Lint = tt.lint
+			cfg.Scopes = tt.scopes
+
+			var rules []string
+			for _, v := range lintMessage(&tt.msg, cfg) {
+				rules = append(rules, v.Rule)
+			}
+
+			if strings.Join(rules, ",") != strings.Join(tt.expectedRules, ",") {
+				t.Errorf("expected violations %v, got %v", tt.expectedRules, rules)
+			}
+		})
+	}
+}
+
+func TestLooksNonImperative(t *testing.T) {
+	tests := map[string]bool{
+		"add":      false,
+		"added":    true,
+		"adding":   true,
+		"adds":     true,
+		"fix":      false,
+		"process":  false,
+		"embed":    false,
+		"focus":    false,
+		"update":   false,
+		"ensure":   false,
+		"bring":    false,
+		"removing": true,
+	}
+
+	for word, expected := range tests {
+		if got := looksNonImperative(word); got != expected {
+			t.Errorf("looksNonImperative(%q): expected %v, got %v", word, expected, got)
+		}
+	}
+}
+
+func TestValidateLintConfig(t *testing.T) {
+	if err := validateLintConfig(map[string]lintRuleConfig{"header-max-length": {Severity: severityWarning}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := validateLintConfig(map[string]lintRuleConfig{"no-such-rule": {}}); err == nil {
+		t.Error("expected error for an unknown rule")
+	}
+	if err := validateLintConfig(map[string]lintRuleConfig{"header-max-length": {Severity: "fatal"}}); err == nil {
+		t.Error("expected error for an invalid severity")
+	}
+}
+
+func TestLintRawMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		expectedRules []string
+	}{
+		{
+			name: "Valid",
+			raw:  "feat: add login form\n",
+		},
+		{
+			name:          "InvalidHeader",
+			raw:           "add login form\n",
+			expectedRules: []string{"header-format"},
+		},
+		{
+			name:          "Empty",
+			raw:           "# only comments\n",
+			expectedRules: []string{"header-format"},
+		},
+		{
+			name:          "Violations",
+			raw:           "feat: add login form.\n",
+			expectedRules: []string{"summary-full-stop"},
+		},
+		{
+			name: "MergeIgnored",
+			raw:  "Merge branch 'main' into feature\n",
+		},
+		{
+			name: "FixupIgnored",
+			raw:  "fixup! feat: add login form\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, v := range lintRawMessage(tt.raw, defaultSettings()) {
+				rules = append(rules, v.Rule)
+			}
+
+			if strings.Join(rules, ",") != strings.Join(tt.expectedRules, ",") {
+				t.Errorf("expected violations %v, got %v", tt.expectedRules, rules)
+			}
+		})
+	}
+}
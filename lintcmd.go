@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// lintReport holds the violations found in one commit message.
+type lintReport struct {
+	File       string          `json:"file,omitempty"`
+	Commit     string          `json:"commit,omitempty"`
+	Header     string          `json:"header"`
+	Violations []lintViolation `json:"violations"`
+}
+
+// source returns the file path or abbreviated commit hash the report refers to.
+func (r lintReport) source() string {
+	if r.File != "" {
+		return r.File
+	}
+	return r.Commit[:min(len(r.Commit), 7)]
+}
+
+// newLintReport lints the raw message and returns the report for it.
+func newLintReport(raw string, cfg *settings) lintReport {
+	header, _, _ := strings.Cut(cleanupMessage(raw), "\n")
+	violations := lintRawMessage(raw, cfg)
+	if violations == nil {
+		violations = []lintViolation{}
+	}
+	return lintReport{Header: header, Violations: violations}
+}
+
+// lintOptions holds the flags of `git cm lint`.
+type lintOptions struct {
+	File   string
+	Range  string
+	Format string
+}
+
+// newLintFlagSet returns the flag set of the lint command, storing the flags in o.
+func newLintFlagSet(o *lintOptions) *flag.FlagSet {
+	fs := newFlagSet("lint")
+	fs.StringVar(&o.File, "file", "", "Lint the commit message in `FILE` (\"-\" reads standard input)")
+	fs.StringVar(&o.Range, "range", "", "Lint the commits in a revision `RANGE` such as origin/main..HEAD")
+	fs.StringVar(&o.Format, "format", "text", "Output `FORMAT`: text or json")
+	return fs
+}
+
+// runLint implements `git cm lint`, which checks commit messages outside the TUI:
+// a message file (as passed to a commit-msg hook) with --file, or the commits of a
+// revision range with --range. It returns exitInvalidMessage when any rule reports an error.
+func runLint(args []string) int {
+	o := &lintOptions{}
+	fs := newLintFlagSet(o)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return exitWithError(fmt.Errorf("unexpected argument: %s", fs.Arg(0)))
+	}
+
+	if (o.File == "") == (o.Range == "") {
+		return exitWithError(fmt.Errorf("exactly one of --file or --range is required"))
+	}
+	if o.Format != "text" && o.Format != "json" {
+		return exitWithError(fmt.Errorf("unknown format %q", o.Format))
+	}
+
+	var reports []lintReport
+	var err error
+	if o.File != "" {
+		reports, err = lintFile(o.File)
+	} else {
+		reports, err = lintRange(o.Range)
+	}
+	if err != nil {
+		return exitWithError(err)
+	}
+
+	if err := writeLintReports(os.Stdout, reports, o.Format); err != nil {
+		return exitWithError(err)
+	}
+
+	for _, r := range reports {
+		if hasLintErrors(r.Violations) {
+			return exitInvalidMessage
+		}
+	}
+	return exitOK
+}
+
+// lintSettings loads the git-cm config of the current repository. Outside a repository,
+// only the current directory is searched for a repository config file.
+func lintSettings() (*settings, error) {
+	return loadSettings(settingsRoot())
+}
+
+// lintFile lints the commit message stored in path, or read from stdin when path is "-".
+func lintFile(path string) ([]lintReport, error) {
+	cfg, err := lintSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit message: %w", err)
+	}
+
+	r := newLintReport(string(data), cfg)
+	r.File = path
+	return []lintReport{r}, nil
+}
+
+// lintRange lints the messages of the commits in the revision range spec.
+func lintRange(spec string) ([]lintReport, error) {
+	loc, err := findRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadSettings(loc.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := openRepo(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := commitsInRange(repo, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]lintReport, 0, len(commits))
+	for _, c := range commits {
+		r := newLintReport(c.Message, cfg)
+		r.Commit = c.Hash.String()
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// writeLintReports writes the reports in the given format. The text format only lists
+// messages with violations; the json format lists every linted message.
+func writeLintReports(w io.Writer, reports []lintReport, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if reports == nil {
+			reports = []lintReport{}
+		}
+		return enc.Encode(reports)
+	}
+
+	for _, r := range reports {
+		if len(r.Violations) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", r.source(), r.Header); err != nil {
+			return err
+		}
+		for _, v := range r.Violations {
+			if _, err := fmt.Fprintf(w, "  %s\n", v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
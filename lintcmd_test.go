@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteLintReports(t *testing.T) {
+	cfg := defaultSettings()
+	clean := newLintReport("feat: add login form\n", cfg)
+	clean.Commit = "1111111111111111111111111111111111111111"
+	broken := newLintReport("feat: Add login form.\n", cfg)
+	broken.Commit = "2222222222222222222222222222222222222222"
+	reports := []lintReport{clean, broken}
+
+	t.Run("Text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeLintReports(&buf, reports, "text"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		out := buf.String()
+		if strings.Contains(out, "1111111") {
+			t.Errorf("text output should not list clean commits, got:\n%s", out)
+		}
+		if !strings.Contains(out, "2222222: feat: Add login form.") {
+			t.Errorf("text output should list the broken commit, got:\n%s", out)
+		}
+		if !strings.Contains(out, "error: summary must not end with a period (summary-full-stop)") {
+			t.Errorf("text output should list the violation, got:\n%s", out)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeLintReports(&buf, reports, "json"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var decoded []lintReport
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("failed to decode JSON output: %v", err)
+		}
+		if len(decoded) != 2 {
+			t.Fatalf("expected 2 reports, got %d", len(decoded))
+		}
+		if len(decoded[0].Violations) != 0 {
+			t.Errorf("expected no violations for the clean commit, got %v", decoded[0].Violations)
+		}
+		if !hasLintErrors(decoded[1].Violations) {
+			t.Errorf("expected errors for the broken commit, got %v", decoded[1].Violations)
+		}
+	})
+}
@@ -1,21 +1,9 @@
 package main
 
-import (
-	"flag"
-	"fmt"
-	"os"
-)
+import "os"
 
 var version = "v0.0.1"
 
 func main() {
-	showVersion := flag.Bool("version", false, "Show version")
-	flag.Parse()
-
-	if *showVersion {
-		fmt.Println(version)
-		os.Exit(0)
-	}
-
-	os.Exit(doCommit())
+	os.Exit(run(os.Args[1:]))
 }
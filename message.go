@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// breakingChangeToken is the footer token that introduces a breaking change description.
+const breakingChangeToken = "BREAKING CHANGE"
+
+// scissorsLine marks the start of the diff appended by `git commit --verbose`;
+// git discards it and everything below it.
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+var (
+	// trailerKeyPattern matches a valid git trailer key such as "Co-authored-by".
+	trailerKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+	// headerPattern matches a Conventional Commits header: "type(scope)!: summary".
+	headerPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)(?:\(([^()]*)\))?(!)?: (.*)$`)
+	// footerPattern matches the first line of a footer: "Key: value" or "BREAKING CHANGE: value".
+	footerPattern = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z0-9][A-Za-z0-9-]*): ?(.*)$`)
+)
+
+// errInvalidHeader is returned by parseMessage when the first line is not a Conventional Commits header.
+var errInvalidHeader = errors.New("header does not follow the \"type(scope): summary\" format")
+
+// trailer is a "Key: Value" line in the trailer block at the end of a commit message.
+type trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// String returns the trailer in git's "Key: Value" format.
+func (t trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// parseTrailer parses a "Key: Value" string into a trailer.
+// The key and value are trimmed, but not validated.
+func parseTrailer(s string) (trailer, error) {
+	key, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return trailer{}, fmt.Errorf("malformed trailer %q: expected \"Key: Value\"", s)
+	}
+	return trailer{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)}, nil
+}
+
+// commitMessage is a struct that holds the fields for a commit message.
+type commitMessage struct {
+	Prefix         string    `json:"prefix"`
+	Scope          string    `json:"scope,omitempty"`
+	Breaking       bool      `json:"breaking,omitempty"`
+	Summary        string    `json:"summary"`
+	Description    string    `json:"description,omitempty"`
+	BreakingChange string    `json:"breaking_change,omitempty"`
+	Trailers       []trailer `json:"trailers,omitempty"`
+}
+
+// empty reports whether nothing was written besides the prefix and the scope,
+// which the TUI may have filled in by itself.
+func (m *commitMessage) empty() bool {
+	return m.Summary == "" && m.Description == "" && m.BreakingChange == "" && len(m.Trailers) == 0
+}
+
+// addTrailer validates t and appends it to the trailers of the message.
+// Malformed keys, empty values and duplicates of an existing trailer are rejected.
+func (m *commitMessage) addTrailer(t trailer) error {
+	if !trailerKeyPattern.MatchString(t.Key) {
+		return fmt.Errorf("malformed trailer key %q", t.Key)
+	}
+	if t.Value == "" {
+		return fmt.Errorf("trailer %q has an empty value", t.Key)
+	}
+
+	for _, existing := range m.Trailers {
+		if strings.EqualFold(existing.Key, t.Key) && existing.Value == t.Value {
+			return fmt.Errorf("duplicate trailer %q", t.String())
+		}
+	}
+
+	m.Trailers = append(m.Trailers, t)
+	return nil
+}
+
+// header returns the first line of the commit message in the Conventional Commits
+// form "prefix(scope)!: summary". The scope part is omitted when Scope is empty,
+// and "!" is only added for breaking changes.
+func (m *commitMessage) header() string {
+	h := m.Prefix
+	if m.Scope != "" {
+		h += "(" + m.Scope + ")"
+	}
+	if m.Breaking {
+		h += "!"
+	}
+	return fmt.Sprintf("%s: %s", h, m.Summary)
+}
+
+// footer returns the trailer block placed after the description: the BREAKING CHANGE
+// footer first, followed by the other trailers. It returns an empty string when there is
+// nothing to emit.
+func (m *commitMessage) footer() string {
+	var lines []string
+	if breakingChange := strings.TrimSpace(m.BreakingChange); m.Breaking && breakingChange != "" {
+		lines = append(lines, breakingChangeToken+": "+breakingChange)
+	}
+	for _, t := range m.Trailers {
+		lines = append(lines, t.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String returns the full commit message: the header, the description and the footer,
+// separated by blank lines.
+func (m *commitMessage) String() string {
+	parts := []string{m.header()}
+	if desc := strings.TrimSpace(m.Description); desc != "" {
+		parts = append(parts, desc)
+	}
+	if footer := m.footer(); footer != "" {
+		parts = append(parts, footer)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// validate checks that the message can be committed with the given settings:
+// the summary must be within the configured limit and no lint rule may report an error.
+func (m *commitMessage) validate(cfg *settings) error {
+	if err := m.checkSummaryLimit(cfg); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidMessage, err)
+	}
+
+	var problems []string
+	for _, v := range lintMessage(m, cfg) {
+		if v.Severity == severityError {
+			problems = append(problems, v.Message)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", errInvalidMessage, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkSummaryLimit returns an error when the summary is longer than summary_limit. The TUI limits
+// the input to it, but the ticket ID inserted in the summary can push it over.
+func (m *commitMessage) checkSummaryLimit(cfg *settings) error {
+	if n := len([]rune(strings.TrimSpace(m.Summary))); n > cfg.SummaryLimit {
+		return fmt.Errorf("summary is %d characters long, limit is %d", n, cfg.SummaryLimit)
+	}
+	return nil
+}
+
+// cleanupMessage strips what git itself removes from an edited commit message:
+// comment lines, the scissors line with everything below it, and surrounding blank lines.
+func cleanupMessage(raw string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n") {
+		if line == scissorsLine {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// parseMessage parses a raw commit message back into a commitMessage.
+// The last paragraph is read as the footer when every line in it is a "Key: value" trailer,
+// a BREAKING CHANGE footer, or a continuation of one. If the header does not follow the
+// Conventional Commits format, the whole header is kept as Summary and errInvalidHeader is returned.
+func parseMessage(raw string) (*commitMessage, error) {
+	lines := strings.Split(cleanupMessage(raw), "\n")
+	m := &commitMessage{}
+
+	var headerErr error
+	if match := headerPattern.FindStringSubmatch(lines[0]); match != nil {
+		m.Prefix = match[1]
+		m.Scope = match[2]
+		m.Breaking = match[3] == "!"
+		m.Summary = match[4]
+	} else {
+		m.Summary = lines[0]
+		headerErr = errInvalidHeader
+	}
+
+	paragraphs := splitParagraphs(lines[1:])
+	if n := len(paragraphs); n > 0 && m.parseFooter(paragraphs[n-1]) {
+		paragraphs = paragraphs[:n-1]
+	}
+
+	body := make([]string, len(paragraphs))
+	for i, p := range paragraphs {
+		body[i] = strings.Join(p, "\n")
+	}
+	m.Description = strings.Join(body, "\n\n")
+	return m, headerErr
+}
+
+// splitParagraphs groups lines into paragraphs separated by blank lines.
+func splitParagraphs(lines []string) [][]string {
+	var paragraphs [][]string
+	var current []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, current)
+	}
+	return paragraphs
+}
+
+// parseFooter reads the paragraph as a footer into the BreakingChange and Trailers fields.
+// It returns false, leaving the message untouched, when the paragraph is not a footer.
+func (m *commitMessage) parseFooter(paragraph []string) bool {
+	var footers []trailer
+	for _, line := range paragraph {
+		if match := footerPattern.FindStringSubmatch(line); match != nil {
+			footers = append(footers, trailer{Key: match[1], Value: match[2]})
+			continue
+		}
+
+		// Continuation lines are indented, except in a BREAKING CHANGE description.
+		n := len(footers)
+		if n == 0 || (!isBreakingChangeKey(footers[n-1].Key) && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t")) {
+			return false
+		}
+		footers[n-1].Value += "\n" + line
+	}
+
+	for _, f := range footers {
+		if isBreakingChangeKey(f.Key) {
+			m.Breaking = true
+			m.BreakingChange = f.Value
+			continue
+		}
+		m.Trailers = append(m.Trailers, f)
+	}
+	return true
+}
+
+// isBreakingChangeKey reports whether key introduces a breaking change footer.
+func isBreakingChangeKey(key string) bool {
+	return key == breakingChangeToken || key == "BREAKING-CHANGE"
+}
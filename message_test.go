@@ -0,0 +1,323 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCommitMessage_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      commitMessage
+		expected string
+	}{
+		{
+			name: "WithoutScope",
+			msg: commitMessage{
+				Prefix:      "feat",
+				Summary:     "add login",
+				Description: "Details.",
+			},
+			expected: "feat: add login\n\nDetails.",
+		},
+		{
+			name: "WithScope",
+			msg: commitMessage{
+				Prefix:      "fix",
+				Scope:       "api",
+				Summary:     "handle timeout",
+				Description: "Details.",
+			},
+			expected: "fix(api): handle timeout\n\nDetails.",
+		},
+		{
+			name: "WithoutDescription",
+			msg: commitMessage{
+				Prefix:  "docs",
+				Summary: "fix typo",
+			},
+			expected: "docs: fix typo",
+		},
+		{
+			name: "BreakingWithFooter",
+			msg: commitMessage{
+				Prefix:         "feat",
+				Scope:          "api",
+				Breaking:       true,
+				Summary:        "drop v1 endpoints",
+				Description:    "Details.",
+				BreakingChange: "the /v1 routes are removed",
+			},
+			expected: "feat(api)!: drop v1 endpoints\n\nDetails.\n\nBREAKING CHANGE: the /v1 routes are removed",
+		},
+		{
+			name: "BreakingWithoutFooter",
+			msg: commitMessage{
+				Prefix:   "refactor",
+				Breaking: true,
+				Summary:  "rename config keys",
+			},
+			expected: "refactor!: rename config keys",
+		},
+		{
+			name: "FooterIgnoredWhenNotBreaking",
+			msg: commitMessage{
+				Prefix:         "fix",
+				Summary:        "handle nil",
+				BreakingChange: "leftover text",
+			},
+			expected: "fix: handle nil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCommitMessage_Trailers(t *testing.T) {
+	m := commitMessage{
+		Prefix:         "feat",
+		Breaking:       true,
+		Summary:        "add pairing mode",
+		BreakingChange: "the --pair flag is removed",
+	}
+
+	for _, s := range []string{
+		"Co-authored-by: Alice <alice@example.com>",
+		"Refs: #42",
+	} {
+		tr, err := parseTrailer(s)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", s, err)
+		}
+		if err := m.addTrailer(tr); err != nil {
+			t.Fatalf("unexpected error adding %q: %v", s, err)
+		}
+	}
+
+	expected := "feat!: add pairing mode\n\n" +
+		"BREAKING CHANGE: the --pair flag is removed\n" +
+		"Co-authored-by: Alice <alice@example.com>\n" +
+		"Refs: #42"
+	if got := m.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestCommitMessage_AddTrailerValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		trailer   trailer
+		expectErr bool
+	}{
+		{
+			name:    "Valid",
+			trailer: trailer{Key: "Reviewed-by", Value: "Bob <bob@example.com>"},
+		},
+		{
+			name:      "KeyWithSpace",
+			trailer:   trailer{Key: "Reviewed by", Value: "Bob <bob@example.com>"},
+			expectErr: true,
+		},
+		{
+			name:      "EmptyKey",
+			trailer:   trailer{Key: "", Value: "value"},
+			expectErr: true,
+		},
+		{
+			name:      "EmptyValue",
+			trailer:   trailer{Key: "Refs", Value: ""},
+			expectErr: true,
+		},
+		{
+			name:      "Duplicate",
+			trailer:   trailer{Key: "signed-off-by", Value: "Tester <tester@example.com>"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := commitMessage{
+				Trailers: []trailer{{Key: "Signed-off-by", Value: "Tester <tester@example.com>"}},
+			}
+
+			err := m.addTrailer(tt.trailer)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseTrailer(t *testing.T) {
+	tr, err := parseTrailer("Refs:  PROJ-1 ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tr.Key != "Refs" || tr.Value != "PROJ-1" {
+		t.Errorf("unexpected trailer %+v", tr)
+	}
+
+	if _, err := parseTrailer("no separator"); err == nil {
+		t.Error("expected error for a trailer without ':'")
+	}
+}
+
+func TestCommitMessage_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       commitMessage
+		expectErr bool
+	}{
+		{
+			name: "Valid",
+			msg:  commitMessage{Prefix: "feat", Summary: "add login"},
+		},
+		{
+			name:      "UnknownPrefix",
+			msg:       commitMessage{Prefix: "unknown", Summary: "add login"},
+			expectErr: true,
+		},
+		{
+			name:      "EmptySummary",
+			msg:       commitMessage{Prefix: "feat", Summary: "  "},
+			expectErr: true,
+		},
+		{
+			name:      "SummaryTooLong",
+			msg:       commitMessage{Prefix: "feat", Summary: strings.Repeat("a", 101)},
+			expectErr: true,
+		},
+		{
+			name: "MalformedTrailer",
+			msg: commitMessage{
+				Prefix:   "feat",
+				Summary:  "add login",
+				Trailers: []trailer{{Key: "Bad key", Value: "value"}},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.msg.validate(defaultSettings())
+			if tt.expectErr {
+				if !errors.Is(err, errInvalidMessage) {
+					t.Errorf("expected errInvalidMessage, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		expected  commitMessage
+		expectErr bool
+	}{
+		{
+			name:     "HeaderOnly",
+			raw:      "feat: add login\n",
+			expected: commitMessage{Prefix: "feat", Summary: "add login"},
+		},
+		{
+			name: "Full",
+			raw: "feat(api)!: drop v1 endpoints\n\nFirst paragraph.\n\nSecond paragraph.\n\n" +
+				"BREAKING CHANGE: the /v1 routes are removed\nuse /v2 instead\n" +
+				"Refs: #42\nCo-authored-by: Alice <alice@example.com>\n",
+			expected: commitMessage{
+				Prefix:         "feat",
+				Scope:          "api",
+				Breaking:       true,
+				Summary:        "drop v1 endpoints",
+				Description:    "First paragraph.\n\nSecond paragraph.",
+				BreakingChange: "the /v1 routes are removed\nuse /v2 instead",
+				Trailers: []trailer{
+					{Key: "Refs", Value: "#42"},
+					{Key: "Co-authored-by", Value: "Alice <alice@example.com>"},
+				},
+			},
+		},
+		{
+			name: "LastParagraphIsNotFooter",
+			raw:  "fix: handle nil\n\nRefs: #1\nthis line is not a trailer\n",
+			expected: commitMessage{
+				Prefix:      "fix",
+				Summary:     "handle nil",
+				Description: "Refs: #1\nthis line is not a trailer",
+			},
+		},
+		{
+			name: "CommentsAndScissors",
+			raw: "docs: update readme\n# Please enter the commit message\n\nBody.\n" +
+				scissorsLine + "\ndiff --git a/README.md b/README.md\n",
+			expected: commitMessage{Prefix: "docs", Summary: "update readme", Description: "Body."},
+		},
+		{
+			name:      "NonConventionalHeader",
+			raw:       "Update README\n",
+			expected:  commitMessage{Summary: "Update README"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := parseMessage(tt.raw)
+			if tt.expectErr {
+				if !errors.Is(err, errInvalidHeader) {
+					t.Errorf("expected errInvalidHeader, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if m.String() != tt.expected.String() || m.Breaking != tt.expected.Breaking ||
+				m.BreakingChange != tt.expected.BreakingChange || len(m.Trailers) != len(tt.expected.Trailers) {
+				t.Errorf("expected %+v, got %+v", tt.expected, *m)
+			}
+		})
+	}
+}
+
+func TestParseMessage_RoundTrip(t *testing.T) {
+	original := &commitMessage{
+		Prefix:         "refactor",
+		Scope:          "ui",
+		Breaking:       true,
+		Summary:        "split the model",
+		Description:    "Line one.\nLine two.",
+		BreakingChange: "newCommitModel takes settings",
+		Trailers:       []trailer{{Key: "Signed-off-by", Value: "Tester <tester@example.com>"}},
+	}
+
+	parsed, err := parseMessage(original.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.String() != original.String() {
+		t.Errorf("expected %q, got %q", original.String(), parsed.String())
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cliOptions holds the command-line options of git-cm.
+// The message flags pre-populate the TUI, or build the commit message directly when Yes is set.
+type cliOptions struct {
+	Version     bool
+	Yes         bool
+	MessageFile string
+	NoVerify    bool
+	Sign        bool
+	NoSign      bool
+	Author      string
+	Date        string
+	Amend       bool
+	ResetAuthor bool
+	Reuse       int // 1-based position in the message history; 0 when not reusing
+
+	Prefix         string
+	Scope          string
+	Summary        string
+	Body           string
+	Breaking       bool
+	BreakingChange string
+	Trailers       []string
+}
+
+// chdirOptions applies the leading -C options and returns the remaining arguments. Like
+// `git -C <dir>`, each option changes the working directory, relative to the previous one,
+// before the command runs, so that git-cm operates on the repository found there.
+// An empty directory leaves the working directory unchanged.
+func chdirOptions(args []string) ([]string, error) {
+	for len(args) > 0 {
+		var dir string
+		switch a := args[0]; {
+		case a == "-C" || a == "--C":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("no directory given for %s", a)
+			}
+			dir, args = args[1], args[2:]
+		case strings.HasPrefix(a, "-C="), strings.HasPrefix(a, "--C="):
+			_, dir, _ = strings.Cut(a, "=")
+			args = args[1:]
+		default:
+			return args, nil
+		}
+
+		if dir == "" {
+			continue
+		}
+		if err := os.Chdir(dir); err != nil {
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				err = pathErr.Err
+			}
+			return nil, fmt.Errorf("cannot change to %s: %w", dir, err)
+		}
+	}
+	return args, nil
+}
+
+// newCommitFlagSet returns the flag set of the commit command, storing the flags in o.
+func newCommitFlagSet(o *cliOptions) *flag.FlagSet {
+	fs := newFlagSet("commit")
+	fs.BoolVar(&o.Version, "version", false, "Show version")
+	fs.BoolVar(&o.Yes, "y", false, "Commit without starting the TUI (shorthand for --yes)")
+	fs.BoolVar(&o.Yes, "yes", false, "Commit without starting the TUI")
+	fs.StringVar(&o.MessageFile, "message-file", "", "Write the message to `FILE` instead of committing (used by the prepare-commit-msg hook)")
+	fs.BoolVar(&o.NoVerify, "n", false, "Skip the pre-commit and commit-msg hooks (shorthand for --no-verify)")
+	fs.BoolVar(&o.NoVerify, "no-verify", false, "Skip the pre-commit and commit-msg hooks")
+	fs.BoolVar(&o.Sign, "S", false, "Sign the commit (shorthand for --gpg-sign)")
+	fs.BoolVar(&o.Sign, "gpg-sign", false, "Sign the commit, even if commit.gpgsign is not set")
+	fs.BoolVar(&o.NoSign, "no-gpg-sign", false, "Do not sign the commit, even if commit.gpgsign is set")
+	fs.StringVar(&o.Author, "author", "", "Override the commit author with `AUTHOR` (\"Name <email>\", or a pattern matching a past author)")
+	fs.StringVar(&o.Date, "date", "", "Override the author `DATE`")
+	fs.BoolVar(&o.Amend, "amend", false, "Replace the last commit, starting from its message and keeping its author")
+	fs.BoolVar(&o.ResetAuthor, "reset-author", false, "With --amend, make the current user the author and reset the author date")
+	fs.Var(reuseFlag{&o.Reuse}, "reuse", "Start from the most recent message of the history, or the `N`-th with --reuse=N")
+	fs.StringVar(&o.Prefix, "t", "", "Commit type, e.g. feat (shorthand for --type)")
+	fs.StringVar(&o.Prefix, "type", "", "Commit type, e.g. feat")
+	fs.StringVar(&o.Summary, "s", "", "Commit summary (shorthand for --summary)")
+	fs.StringVar(&o.Summary, "summary", "", "Commit summary")
+	fs.StringVar(&o.Body, "b", "", "Commit body (shorthand for --body)")
+	fs.StringVar(&o.Body, "body", "", "Commit body")
+	fs.StringVar(&o.Scope, "scope", "", "Commit scope")
+	fs.BoolVar(&o.Breaking, "breaking", false, "Mark the commit as a breaking change")
+	fs.StringVar(&o.BreakingChange, "breaking-change", "", "BREAKING CHANGE footer text (implies --breaking)")
+	fs.Func("trailer", "Trailer in \"Key: Value\" form (repeatable)", func(s string) error {
+		o.Trailers = append(o.Trailers, s)
+		return nil
+	})
+	return fs
+}
+
+// parseOptions parses the command-line arguments (without the program name).
+func parseOptions(args []string) (*cliOptions, error) {
+	o := &cliOptions{}
+
+	fs := newCommitFlagSet(o)
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	if o.Sign && o.NoSign {
+		return nil, fmt.Errorf("--gpg-sign and --no-gpg-sign cannot be used together")
+	}
+	if o.ResetAuthor && !o.Amend {
+		return nil, fmt.Errorf("--reset-author can only be used with --amend")
+	}
+	if o.Amend && o.MessageFile != "" {
+		return nil, fmt.Errorf("--amend cannot be used with --message-file")
+	}
+	if o.Amend && o.Reuse > 0 {
+		return nil, fmt.Errorf("--reuse cannot be used with --amend")
+	}
+	if o.BreakingChange != "" {
+		o.Breaking = true
+	}
+	return o, nil
+}
+
+// hasMessage reports whether any of the message flags were given.
+func (o *cliOptions) hasMessage() bool {
+	return o.Prefix != "" || o.Scope != "" || o.Summary != "" || o.Body != "" ||
+		o.Breaking || len(o.Trailers) > 0
+}
+
+// message builds a commitMessage from the message flags. When base is not nil (the message
+// of the amended commit or a reused message), the flags override its fields and add to its trailers.
+// The prefix falls back to defaultPrefix when it is set neither by base nor by --type.
+func (o *cliOptions) message(base *commitMessage, defaultPrefix string) (*commitMessage, error) {
+	m := &commitMessage{}
+	if base != nil {
+		*m = *base
+		m.Trailers = append([]trailer(nil), base.Trailers...)
+	}
+
+	for _, f := range []struct {
+		value string
+		field *string
+	}{
+		{o.Prefix, &m.Prefix},
+		{o.Scope, &m.Scope},
+		{o.Summary, &m.Summary},
+		{o.Body, &m.Description},
+		{o.BreakingChange, &m.BreakingChange},
+	} {
+		if f.value != "" {
+			*f.field = f.value
+		}
+	}
+	m.Breaking = m.Breaking || o.Breaking
+	if m.Prefix == "" {
+		m.Prefix = defaultPrefix
+	}
+
+	for _, s := range o.Trailers {
+		t, err := parseTrailer(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidMessage, err)
+		}
+		if err := m.addTrailer(t); err != nil {
+			return nil, fmt.Errorf("%w: %w", errInvalidMessage, err)
+		}
+	}
+	return m, nil
+}
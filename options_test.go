@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		expected  cliOptions
+		expectErr bool
+	}{
+		{
+			name:     "NoArguments",
+			args:     nil,
+			expected: cliOptions{},
+		},
+		{
+			name: "ShortFlags",
+			args: []string{"-t", "fix", "-s", "handle nil", "-b", "body", "-y"},
+			expected: cliOptions{
+				Yes:     true,
+				Prefix:  "fix",
+				Summary: "handle nil",
+				Body:    "body",
+			},
+		},
+		{
+			name: "LongFlags",
+			args: []string{"--type", "feat", "--scope", "api", "--summary", "add", "--yes",
+				"--trailer", "Refs: #1", "--trailer", "Refs: #2"},
+			expected: cliOptions{
+				Yes:      true,
+				Prefix:   "feat",
+				Scope:    "api",
+				Summary:  "add",
+				Trailers: []string{"Refs: #1", "Refs: #2"},
+			},
+		},
+		{
+			name: "BreakingChangeImpliesBreaking",
+			args: []string{"--breaking-change", "removed"},
+			expected: cliOptions{
+				Breaking:       true,
+				BreakingChange: "removed",
+			},
+		},
+		{
+			name:     "NoVerify",
+			args:     []string{"-n", "-y"},
+			expected: cliOptions{Yes: true, NoVerify: true},
+		},
+		{
+			name:     "Sign",
+			args:     []string{"-S"},
+			expected: cliOptions{Sign: true},
+		},
+		{
+			name:      "SignConflict",
+			args:      []string{"--gpg-sign", "--no-gpg-sign"},
+			expectErr: true,
+		},
+		{
+			name:     "AuthorAndDate",
+			args:     []string{"--author", "Alice <alice@example.com>", "--date", "2024-01-02"},
+			expected: cliOptions{Author: "Alice <alice@example.com>", Date: "2024-01-02"},
+		},
+		{
+			name:     "AmendResetAuthor",
+			args:     []string{"--amend", "--reset-author"},
+			expected: cliOptions{Amend: true, ResetAuthor: true},
+		},
+		{
+			name:      "ResetAuthorWithoutAmend",
+			args:      []string{"--reset-author"},
+			expectErr: true,
+		},
+		{
+			name:     "Reuse",
+			args:     []string{"--reuse", "-y"},
+			expected: cliOptions{Reuse: 1, Yes: true},
+		},
+		{
+			name:     "ReuseNth",
+			args:     []string{"--reuse=3"},
+			expected: cliOptions{Reuse: 3},
+		},
+		{
+			name:      "ReuseInvalid",
+			args:      []string{"--reuse=0"},
+			expectErr: true,
+		},
+		{
+			name:      "ReuseWithAmend",
+			args:      []string{"--reuse", "--amend"},
+			expectErr: true,
+		},
+		{
+			name:      "UnknownFlag",
+			args:      []string{"--unknown"},
+			expectErr: true,
+		},
+		{
+			name:      "UnexpectedArgument",
+			args:      []string{"extra"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := parseOptions(tt.args)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(*o, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, *o)
+			}
+		})
+	}
+}
+
+func TestCLIOptions_Message(t *testing.T) {
+	o := &cliOptions{Summary: "add login", Trailers: []string{"Refs: #1"}}
+	m, err := o.message(nil, "feat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := m.String(); got != "feat: add login\n\nRefs: #1" {
+		t.Errorf("unexpected message %q", got)
+	}
+
+	o = &cliOptions{Summary: "add login", Trailers: []string{"no separator"}}
+	if _, err := o.message(nil, "feat"); !errors.Is(err, errInvalidMessage) {
+		t.Errorf("expected errInvalidMessage, got %v", err)
+	}
+}
+
+func TestCLIOptions_MessageAmend(t *testing.T) {
+	base := &commitMessage{
+		Prefix:      "fix",
+		Scope:       "api",
+		Summary:     "handle timeouts",
+		Description: "Retry once.",
+		Trailers:    []trailer{{Key: "Refs", Value: "#1"}},
+	}
+
+	o := &cliOptions{Summary: "handle connection timeouts", Trailers: []string{"Reviewed-by: Alice <alice@example.com>"}}
+	m, err := o.message(base, "feat")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "fix(api): handle connection timeouts\n\nRetry once.\n\nRefs: #1\nReviewed-by: Alice <alice@example.com>"
+	if got := m.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if len(base.Trailers) != 1 {
+		t.Errorf("expected the base message to be left untouched, got %+v", base.Trailers)
+	}
+}
+
+func TestChdirOptions(t *testing.T) {
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(origDir)
+	}()
+
+	base := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(base, "a", "b"), 0755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		args         []string
+		expectedDir  string // relative to base
+		expectedArgs []string
+		expectErr    bool
+	}{
+		{name: "NoOption", args: []string{"-y"}, expectedDir: ".", expectedArgs: []string{"-y"}},
+		{name: "Directory", args: []string{"-C", "a", "lint"}, expectedDir: "a", expectedArgs: []string{"lint"}},
+		{name: "EqualsForm", args: []string{"-C=a"}, expectedDir: "a", expectedArgs: []string{}},
+		{name: "Relative", args: []string{"-C", "a", "-C", "b", "-y"}, expectedDir: "a/b", expectedArgs: []string{"-y"}},
+		{name: "Empty", args: []string{"-C", "", "-y"}, expectedDir: ".", expectedArgs: []string{"-y"}},
+		{name: "OnlyLeading", args: []string{"-y", "-C", "a"}, expectedDir: ".", expectedArgs: []string{"-y", "-C", "a"}},
+		{name: "MissingDirectory", args: []string{"-C"}, expectErr: true},
+		{name: "NonexistentDirectory", args: []string{"-C", "missing"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chdir(base); err != nil {
+				t.Fatalf("failed to change directory: %v", err)
+			}
+
+			args, err := chdirOptions(tt.args)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("expected args %q, got %q", tt.expectedArgs, args)
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("failed to get current directory: %v", err)
+			}
+			if expected := filepath.Join(base, tt.expectedDir); !sameFile(t, cwd, expected) {
+				t.Errorf("expected directory %q, got %q", expected, cwd)
+			}
+		})
+	}
+}
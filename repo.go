@@ -1,56 +1,390 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/filesystem/dotgit"
 )
 
-// commitMessage is a struct that holds the fields for a commit message.
-type commitMessage struct {
-	Prefix      string
-	Summary     string
-	Description string
+// commitOptions holds the options of commitRepo.
+type commitOptions struct {
+	// NoVerify skips the pre-commit and commit-msg hooks, like `git commit --no-verify`.
+	NoVerify bool
+	// Signer signs the commit when it is not nil.
+	Signer git.Signer
+	// Committer is the committer identity; the author is used when it is nil.
+	Committer *author
+	// AuthorDate and CommitterDate default to the current time when they are zero.
+	AuthorDate    time.Time
+	CommitterDate time.Time
+	// Amend replaces the HEAD commit, like `git commit --amend`, instead of adding a commit on top of it.
+	Amend bool
 }
 
-// findRepoRoot searches upward from the current working directory until it finds
-// the root of a Git repository (i.e. a directory containing a ".git" folder).
-func findRepoRoot() (string, error) {
+// repoLocation is where a Git repository was found.
+type repoLocation struct {
+	// Root is the top-level directory of the worktree.
+	Root string
+	// GitDir is the git directory of the worktree: the ".git" directory, the directory a ".git"
+	// file points to in linked worktrees and submodules, or $GIT_DIR.
+	GitDir string
+	// detected is true when GitDir was found through Root/.git rather than given by the environment.
+	detected bool
+}
+
+// findRepo locates the repository of the current working directory like git does: $GIT_DIR and
+// $GIT_WORK_TREE are used when they are set, otherwise the directories are searched upward for a
+// ".git" directory or file, stopping before the directories listed in $GIT_CEILING_DIRECTORIES.
+// When $GIT_DIR is set without $GIT_WORK_TREE, the current directory is the top of the worktree.
+// A bare repository is reported with errBareRepository since it has no worktree to commit from.
+func findRepo() (*repoLocation, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
-		return "", fmt.Errorf("failed to get current directory: %w", err)
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	workTree := os.Getenv("GIT_WORK_TREE")
+	if workTree != "" {
+		if workTree, err = filepath.Abs(workTree); err != nil {
+			return nil, fmt.Errorf("invalid GIT_WORK_TREE: %w", err)
+		}
+	}
+
+	if dir := os.Getenv("GIT_DIR"); dir != "" {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GIT_DIR: %w", err)
+		}
+		if dir, err = resolveGitDir(dir); err != nil {
+			return nil, err
+		}
+		if workTree == "" {
+			bare, err := isBareRepo(dir)
+			if err != nil {
+				return nil, err
+			}
+			if bare {
+				return nil, fmt.Errorf("%w: %s (set GIT_WORK_TREE to commit from a worktree)", errBareRepository, dir)
+			}
+			workTree = cwd
+		}
+		return &repoLocation{Root: workTree, GitDir: dir}, nil
 	}
 
+	ceilings := ceilingDirs()
 	dir := cwd
 	for {
 		gitPath := filepath.Join(dir, ".git")
-		if info, err := os.Stat(gitPath); err == nil && info.IsDir() {
-			return dir, nil
+		if _, err := os.Stat(gitPath); err == nil {
+			gd, err := resolveGitDir(gitPath)
+			if err != nil {
+				return nil, err
+			}
+			if workTree != "" {
+				return &repoLocation{Root: workTree, GitDir: gd}, nil
+			}
+			return &repoLocation{Root: dir, GitDir: gd, detected: true}, nil
+		}
+		// A git directory found in place of a ".git" is a bare repository, unless it is the
+		// ".git" of a worktree above.
+		if workTree == "" && isGitDir(dir) {
+			bare, err := isBareRepo(dir)
+			if err != nil {
+				return nil, err
+			}
+			if bare {
+				return nil, fmt.Errorf("%w: %s", errBareRepository, dir)
+			}
 		}
 
 		parent := filepath.Dir(dir)
-		if parent == dir {
-			return "", fmt.Errorf("git repository not found")
+		if parent == dir || isCeilingDir(parent, ceilings) {
+			return nil, fmt.Errorf("git repository not found")
 		}
 
 		dir = parent
 	}
 }
 
-// openRepo opens the Git repository located at the specified directory.
-// The provided directory is expected to be the root of a valid Git repository.
-func openRepo(dir string) (*git.Repository, error) {
-	repo, err := git.PlainOpen(dir)
+// resolveGitDir returns the git directory at p: p itself when it is a directory, or the
+// directory named by the "gitdir:" line of a ".git" file, relative to the file's directory.
+func resolveGitDir(p string) (string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	if info.IsDir() {
+		return p, nil
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+	dir, ok := strings.CutPrefix(strings.TrimSpace(line), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("invalid gitfile format: %s", p)
+	}
+	dir = strings.TrimSpace(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(p), dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("not a git repository: %s", dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// isGitDir reports whether dir looks like a git directory, as git checks it while searching for
+// a repository: it has a HEAD file and objects and refs directories.
+func isGitDir(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	for _, name := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// isBareRepo reports whether core.bare is set in the config of the git directory dir.
+func isBareRepo(dir string) (bool, error) {
+	common, err := resolveCommonDir(dir)
+	if err != nil {
+		return false, err
+	}
+	c, err := loadGitConfig(common)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository at %s: %w", dir, err)
+		return false, err
+	}
+	return parseGitBool(c.value("core", "", "bare"))
+}
+
+// ceilingDirs returns the absolute directories of $GIT_CEILING_DIRECTORIES, with their symbolic
+// links resolved. Empty and relative entries are ignored, like git does.
+func ceilingDirs() []string {
+	var dirs []string
+	for _, d := range filepath.SplitList(os.Getenv("GIT_CEILING_DIRECTORIES")) {
+		if d == "" || !filepath.IsAbs(d) {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(d); err == nil {
+			d = resolved
+		}
+		dirs = append(dirs, filepath.Clean(d))
+	}
+	return dirs
+}
+
+// isCeilingDir reports whether the repository search must not go up into dir.
+func isCeilingDir(dir string, ceilings []string) bool {
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	return slices.Contains(ceilings, dir)
+}
+
+// openRepo opens the Git repository found by findRepo. A discovered repository is opened by go-git
+// from its worktree, following the ".git" file of linked worktrees and submodules, and sharing the
+// objects, refs and config of the main repository through its common directory; commits are then
+// made on the HEAD and index of the worktree. A git directory given by $GIT_DIR is opened the same way.
+func openRepo(loc *repoLocation) (*git.Repository, error) {
+	var repo *git.Repository
+	var err error
+	if loc.detected {
+		repo, err = git.PlainOpenWithOptions(loc.Root, &git.PlainOpenOptions{
+			DetectDotGit:          true,
+			EnableDotGitCommonDir: true,
+		})
+	} else {
+		repo, err = openGitDir(loc.GitDir, loc.Root)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository at %s: %w", loc.Root, err)
 	}
 	return repo, nil
 }
 
+// openGitDir opens the repository stored in dir with its worktree at root.
+func openGitDir(dir, root string) (*git.Repository, error) {
+	common, err := resolveCommonDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var fs billy.Filesystem = osfs.New(dir)
+	if common != dir {
+		fs = dotgit.NewRepositoryFilesystem(fs, osfs.New(common))
+	}
+	s := filesystem.NewStorage(fs, cache.NewObjectLRUDefault())
+	return git.Open(s, osfs.New(root))
+}
+
+// gitDir returns the path of the worktree's git directory (usually "<root>/.git"), which holds
+// its HEAD, index and COMMIT_EDITMSG.
+func gitDir(r *git.Repository) (string, error) {
+	fs, ok := r.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", fmt.Errorf("repository is not stored on the filesystem")
+	}
+	return fs.Filesystem().Root(), nil
+}
+
+// commonDir returns the git directory shared by all the worktrees of the repository, which holds
+// the config and the hooks. It is the git directory itself, except in a linked worktree.
+func commonDir(r *git.Repository) (string, error) {
+	dir, err := gitDir(r)
+	if err != nil {
+		return "", err
+	}
+	return resolveCommonDir(dir)
+}
+
+// resolveCommonDir returns the directory named by the "commondir" file of the git directory dir,
+// relative to dir, or dir itself when there is no such file.
+func resolveCommonDir(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "commondir"))
+	if errors.Is(err, os.ErrNotExist) {
+		return dir, nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read commondir: %w", err)
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(dir, common)
+	}
+	return filepath.Clean(common), nil
+}
+
+// currentBranch returns the short name of the branch checked out at HEAD, including an unborn
+// branch, or an empty string when HEAD is detached.
+func currentBranch(r *git.Repository) (string, error) {
+	ref, err := r.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if ref.Type() != plumbing.SymbolicReference || !ref.Target().IsBranch() {
+		return "", nil
+	}
+	return ref.Target().Short(), nil
+}
+
+// collectAuthors returns the distinct authors of the last limit commits reachable from HEAD,
+// formatted as "Name <email>" and ordered from the most recent. An empty repository has no authors.
+func collectAuthors(r *git.Repository, limit int) ([]string, error) {
+	head, err := r.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	commits, err := r.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit log: %w", err)
+	}
+	defer commits.Close()
+
+	var authors []string
+	seen := make(map[string]bool)
+	count := 0
+	err = commits.ForEach(func(c *object.Commit) error {
+		if count >= limit {
+			return storer.ErrStop
+		}
+		count++
+
+		a := author{Name: c.Author.Name, Email: c.Author.Email}.String()
+		if !seen[a] {
+			seen[a] = true
+			authors = append(authors, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate commits: %w", err)
+	}
+	return authors, nil
+}
+
+// resolveCommit resolves a revision such as "HEAD~2" or "origin/main" to a commit.
+// An empty revision resolves to HEAD.
+func resolveCommit(r *git.Repository, rev string) (*object.Commit, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	h, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve revision %q: %w", rev, err)
+	}
+
+	c, err := r.CommitObject(*h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", h, err)
+	}
+	return c, nil
+}
+
+// commitsInRange returns the commits selected by spec, newest first.
+// "A..B" selects the commits reachable from B but not from A, like `git log A..B`;
+// either side defaults to HEAD. A single revision selects only that commit.
+func commitsInRange(r *git.Repository, spec string) ([]*object.Commit, error) {
+	from, to, isRange := strings.Cut(spec, "..")
+	if !isRange {
+		c, err := resolveCommit(r, spec)
+		if err != nil {
+			return nil, err
+		}
+		return []*object.Commit{c}, nil
+	}
+
+	base, err := resolveCommit(r, from)
+	if err != nil {
+		return nil, err
+	}
+	tip, err := resolveCommit(r, to)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[plumbing.Hash]bool)
+	err = object.NewCommitPreorderIter(base, nil, nil).ForEach(func(c *object.Commit) error {
+		excluded[c.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk commits from %s: %w", base.Hash, err)
+	}
+
+	var commits []*object.Commit
+	err = object.NewCommitPreorderIter(tip, excluded, nil).ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk commits from %s: %w", tip.Hash, err)
+	}
+	return commits, nil
+}
+
 // checkStagedFiles checks if there are any staged files in the repository.
 // If no staged files are found, it returns an error.
 func checkStagedFiles(wt *git.Worktree) error {
@@ -65,32 +399,91 @@ func checkStagedFiles(wt *git.Worktree) error {
 		}
 	}
 
-	return fmt.Errorf("no files are staged")
+	return errNothingStaged
 }
 
 // commitRepo commits changes using the provided commit message and author information.
+// Like `git commit`, it runs the pre-commit and commit-msg hooks (unless opts.NoVerify is set),
+// which may abort the commit or rewrite the message, and the post-commit hook afterwards.
+// When amending, nothing needs to be staged and the post-rewrite hook is run as well.
 // It returns the commit hash or an error.
-func commitRepo(r *git.Repository, a author, m *commitMessage) (string, error) {
+func commitRepo(r *git.Repository, a author, m *commitMessage, opts commitOptions) (string, error) {
 	wt, err := r.Worktree()
 	if err != nil {
 		return "", fmt.Errorf("failed to get worktree: %w", err)
 	}
 
-	if err := checkStagedFiles(wt); err != nil {
+	hooks, err := newHookRunner(r, wt.Filesystem.Root())
+	if err != nil {
 		return "", err
 	}
 
-	msg := fmt.Sprintf("%s: %s\n\n%s", m.Prefix, m.Summary, m.Description)
+	if !opts.NoVerify {
+		if err := hooks.run("pre-commit"); err != nil {
+			return "", fmt.Errorf("%w: %w", errCommitFailed, err)
+		}
+	}
 
-	h, err := wt.Commit(msg, &git.CommitOptions{
+	var head *object.Commit
+	if opts.Amend {
+		if head, err = resolveCommit(r, ""); err != nil {
+			return "", err
+		}
+	} else if err := checkStagedFiles(wt); err != nil {
+		return "", err
+	}
+
+	msg := m.String()
+	if !opts.NoVerify {
+		msg, err = hooks.runCommitMsg(msg)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", errCommitFailed, err)
+		}
+	}
+
+	now := time.Now()
+	authorDate, committerDate := opts.AuthorDate, opts.CommitterDate
+	if authorDate.IsZero() {
+		authorDate = now
+	}
+	if committerDate.IsZero() {
+		committerDate = now
+	}
+	committer := a
+	if opts.Committer != nil {
+		committer = *opts.Committer
+	}
+
+	commitOpts := &git.CommitOptions{
 		Author: &object.Signature{
 			Name:  a.Name,
 			Email: a.Email,
-			When:  time.Now(),
+			When:  authorDate,
 		},
-	})
+		Committer: &object.Signature{
+			Name:  committer.Name,
+			Email: committer.Email,
+			When:  committerDate,
+		},
+		Signer: opts.Signer,
+	}
+	if head != nil {
+		// go-git's Amend drops all parents but the first, so it is only used for a root commit,
+		// which has no parent to pass explicitly. Merges may be amended without changes, as in git.
+		commitOpts.Amend = len(head.ParentHashes) == 0
+		commitOpts.Parents = head.ParentHashes
+		commitOpts.AllowEmptyCommits = len(head.ParentHashes) > 1
+	}
+
+	h, err := wt.Commit(msg, commitOpts)
 	if err != nil {
-		return "", fmt.Errorf("failed to commit: %w", err)
+		return "", fmt.Errorf("%w: %w", errCommitFailed, err)
+	}
+
+	// Like git, the exit status of post-commit and post-rewrite does not affect the commit.
+	_ = hooks.run("post-commit")
+	if head != nil {
+		_ = hooks.runInput("post-rewrite", fmt.Sprintf("%s %s\n", head.Hash, h), "amend")
 	}
 
 	return h.String(), nil
@@ -1,18 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-func TestFindRepoRoot(t *testing.T) {
+func TestFindRepo(t *testing.T) {
 	origDir, err := os.Getwd()
 	if err != nil {
 		t.Fatalf("failed to get current directory: %v", err)
@@ -20,6 +25,7 @@ func TestFindRepoRoot(t *testing.T) {
 	defer func() {
 		_ = os.Chdir(origDir)
 	}()
+	unsetRepoEnv(t)
 
 	tests := []struct {
 		name    string
@@ -80,6 +86,97 @@ func TestFindRepoRoot(t *testing.T) {
 				return cleanup, "", true
 			},
 		},
+		{
+			name: "GitFile",
+			prepare: func(t *testing.T) (func(), string, bool) {
+				tmpDir := t.TempDir()
+				if err := os.Mkdir(filepath.Join(tmpDir, "repo.git"), 0755); err != nil {
+					t.Fatalf("failed to create git directory: %v", err)
+				}
+				wtDir := filepath.Join(tmpDir, "worktree")
+				if err := os.MkdirAll(filepath.Join(wtDir, "sub"), 0755); err != nil {
+					t.Fatalf("failed to create worktree: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(wtDir, ".git"), []byte("gitdir: ../repo.git\n"), 0644); err != nil {
+					t.Fatalf("failed to write .git file: %v", err)
+				}
+
+				if err := os.Chdir(filepath.Join(wtDir, "sub")); err != nil {
+					t.Fatalf("failed to change directory: %v", err)
+				}
+
+				cleanup := func() {
+					_ = os.Chdir(origDir)
+				}
+				return cleanup, wtDir, false
+			},
+		},
+		{
+			name: "StopsAtCeilingDirectory",
+			prepare: func(t *testing.T) (func(), string, bool) {
+				tmpDir := t.TempDir()
+				if err := os.Mkdir(filepath.Join(tmpDir, ".git"), 0755); err != nil {
+					t.Fatalf("failed to create .git directory: %v", err)
+				}
+				subDir := filepath.Join(tmpDir, "a", "b")
+				if err := os.MkdirAll(subDir, 0755); err != nil {
+					t.Fatalf("failed to create subdirectory: %v", err)
+				}
+				t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Join(tmpDir, "a"))
+
+				if err := os.Chdir(subDir); err != nil {
+					t.Fatalf("failed to change directory: %v", err)
+				}
+
+				cleanup := func() {
+					_ = os.Chdir(origDir)
+				}
+				return cleanup, "", true
+			},
+		},
+		{
+			name: "GitDirEnvironment",
+			prepare: func(t *testing.T) (func(), string, bool) {
+				tmpDir := t.TempDir()
+				if err := os.Mkdir(filepath.Join(tmpDir, "repo.git"), 0755); err != nil {
+					t.Fatalf("failed to create git directory: %v", err)
+				}
+				workDir := filepath.Join(tmpDir, "work")
+				if err := os.Mkdir(workDir, 0755); err != nil {
+					t.Fatalf("failed to create work directory: %v", err)
+				}
+				t.Setenv("GIT_DIR", filepath.Join(tmpDir, "repo.git"))
+
+				if err := os.Chdir(workDir); err != nil {
+					t.Fatalf("failed to change directory: %v", err)
+				}
+
+				cleanup := func() {
+					_ = os.Chdir(origDir)
+				}
+				return cleanup, workDir, false
+			},
+		},
+		{
+			name: "WorkTreeEnvironment",
+			prepare: func(t *testing.T) (func(), string, bool) {
+				tmpDir := t.TempDir()
+				if err := os.Mkdir(filepath.Join(tmpDir, ".git"), 0755); err != nil {
+					t.Fatalf("failed to create .git directory: %v", err)
+				}
+				workDir := t.TempDir()
+				t.Setenv("GIT_WORK_TREE", workDir)
+
+				if err := os.Chdir(tmpDir); err != nil {
+					t.Fatalf("failed to change directory: %v", err)
+				}
+
+				cleanup := func() {
+					_ = os.Chdir(origDir)
+				}
+				return cleanup, workDir, false
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -87,7 +184,7 @@ func TestFindRepoRoot(t *testing.T) {
 			cleanup, expected, expectErr := tt.prepare(t)
 			defer cleanup()
 
-			root, err := findRepoRoot()
+			loc, err := findRepo()
 			if expectErr {
 				if err == nil {
 					t.Error("expected error, but got nil")
@@ -96,7 +193,7 @@ func TestFindRepoRoot(t *testing.T) {
 			}
 
 			if err != nil {
-				t.Errorf("unexpected error: %v", err)
+				t.Fatalf("unexpected error: %v", err)
 			}
 
 			canonicalExpected, err := filepath.EvalSymlinks(expected)
@@ -104,7 +201,7 @@ func TestFindRepoRoot(t *testing.T) {
 				t.Fatalf("failed to evaluate expected symlinks: %v", err)
 			}
 
-			canonicalRoot, err := filepath.EvalSymlinks(root)
+			canonicalRoot, err := filepath.EvalSymlinks(loc.Root)
 			if err != nil {
 				t.Fatalf("failed to evaluate root symlinks: %v", err)
 			}
@@ -157,7 +254,7 @@ func TestOpenRepo(t *testing.T) {
 			dir, cleanup := tt.setup(t)
 			defer cleanup()
 
-			repo, err := openRepo(dir)
+			repo, err := openRepo(&repoLocation{Root: dir, GitDir: filepath.Join(dir, ".git"), detected: true})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("openRepo() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -169,6 +266,271 @@ func TestOpenRepo(t *testing.T) {
 	}
 }
 
+func TestFindRepo_Bare(t *testing.T) {
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(origDir)
+	}()
+	unsetRepoEnv(t)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	bareDir := t.TempDir()
+	if _, err := git.PlainInit(bareDir, true); err != nil {
+		t.Fatalf("failed to init bare repo: %v", err)
+	}
+	repoDir := t.TempDir()
+	if _, err := git.PlainInit(repoDir, false); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		dir          string
+		env          map[string]string
+		expectedRoot string // empty when errBareRepository is expected
+	}{
+		{name: "BareRepository", dir: bareDir},
+		{name: "InsideBareRepository", dir: filepath.Join(bareDir, "refs")},
+		{name: "BareGitDirEnvironment", dir: repoDir, env: map[string]string{"GIT_DIR": bareDir}},
+		{name: "BareWithWorkTree", dir: repoDir, env: map[string]string{"GIT_DIR": bareDir, "GIT_WORK_TREE": repoDir}, expectedRoot: repoDir},
+		{name: "InsideGitDirectory", dir: filepath.Join(repoDir, ".git"), expectedRoot: repoDir},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			if err := os.Chdir(tt.dir); err != nil {
+				t.Fatalf("failed to change directory: %v", err)
+			}
+
+			loc, err := findRepo()
+			if tt.expectedRoot == "" {
+				if !errors.Is(err, errBareRepository) {
+					t.Errorf("expected errBareRepository, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !sameFile(t, loc.Root, tt.expectedRoot) {
+				t.Errorf("expected root %q, got %q", tt.expectedRoot, loc.Root)
+			}
+		})
+	}
+}
+
+func TestResolveGitDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	gitDir := filepath.Join(tmpDir, "repo.git")
+	if err := os.Mkdir(gitDir, 0755); err != nil {
+		t.Fatalf("failed to create git directory: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		content   string // content of the .git file; empty for gitDir itself
+		expectErr bool
+	}{
+		{name: "Directory"},
+		{name: "RelativeGitFile", content: "gitdir: repo.git\n"},
+		{name: "AbsoluteGitFile", content: "gitdir: " + gitDir + "\n"},
+		{name: "InvalidGitFile", content: "repo.git\n", expectErr: true},
+		{name: "MissingTarget", content: "gitdir: missing.git\n", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := gitDir
+			if tt.content != "" {
+				p = filepath.Join(tmpDir, tt.name)
+				if err := os.WriteFile(p, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("failed to write .git file: %v", err)
+				}
+			}
+
+			dir, err := resolveGitDir(p)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", dir)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if dir != gitDir {
+				t.Errorf("expected %q, got %q", gitDir, dir)
+			}
+		})
+	}
+}
+
+func TestOpenRepo_LinkedWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	unsetRepoEnv(t)
+
+	tmpDir := t.TempDir()
+	mainDir := filepath.Join(tmpDir, "main")
+	main, err := git.PlainInit(mainDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	mainWt, err := main.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	initial, err := mainWt.Commit("chore: initial commit", &git.CommitOptions{
+		AllowEmptyCommits: true,
+		Author:            &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	wtDir := filepath.Join(tmpDir, "linked")
+	if out, err := exec.Command("git", "-C", mainDir, "worktree", "add", "-q", "-b", "feature", wtDir).CombinedOutput(); err != nil {
+		t.Fatalf("failed to add worktree: %v: %s", err, out)
+	}
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	defer func() {
+		_ = os.Chdir(origDir)
+	}()
+	if err := os.Chdir(wtDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+
+	loc, err := findRepo()
+	if err != nil {
+		t.Fatalf("failed to find repo: %v", err)
+	}
+	repo, err := openRepo(loc)
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+
+	common, err := commonDir(repo)
+	if err != nil {
+		t.Fatalf("failed to get common dir: %v", err)
+	}
+	if expected := filepath.Join(mainDir, ".git"); !sameFile(t, common, expected) {
+		t.Errorf("expected common dir %q, got %q", expected, common)
+	}
+	if branch, err := currentBranch(repo); err != nil || branch != "feature" {
+		t.Errorf("expected branch %q, got %q (err: %v)", "feature", branch, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(wtDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add("file.txt"); err != nil {
+		t.Fatalf("failed to stage file: %v", err)
+	}
+	a := author{Name: "Alice", Email: "alice@example.com"}
+	hash, err := commitRepo(repo, a, &commitMessage{Prefix: "feat", Summary: "Add file"}, commitOptions{})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	// The commit lands on the worktree's branch, and the main worktree is left untouched.
+	feature, err := main.Reference(plumbing.NewBranchReferenceName("feature"), true)
+	if err != nil {
+		t.Fatalf("failed to get feature branch: %v", err)
+	}
+	if feature.Hash().String() != hash {
+		t.Errorf("expected feature at %s, got %s", hash, feature.Hash())
+	}
+	head, err := main.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+	if head.Hash() != initial {
+		t.Errorf("expected main HEAD at %s, got %s", initial, head.Hash())
+	}
+	if out, err := exec.Command("git", "-C", wtDir, "status", "--porcelain").CombinedOutput(); err != nil || len(out) > 0 {
+		t.Errorf("expected a clean worktree, got %q (err: %v)", out, err)
+	}
+}
+
+func TestOpenRepo_GitDirEnvironment(t *testing.T) {
+	repoDir := t.TempDir()
+	if _, err := git.PlainInit(repoDir, false); err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	repo, err := openRepo(&repoLocation{Root: workDir, GitDir: filepath.Join(repoDir, ".git")})
+	if err != nil {
+		t.Fatalf("failed to open repo: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add("file.txt"); err != nil {
+		t.Fatalf("failed to stage file: %v", err)
+	}
+
+	// The index of the git directory tracks the file of the separate worktree.
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	if len(idx.Entries) != 1 || idx.Entries[0].Name != "file.txt" {
+		t.Errorf("expected file.txt in the index, got %v", idx.Entries)
+	}
+	if dir, err := gitDir(repo); err != nil || dir != filepath.Join(repoDir, ".git") {
+		t.Errorf("expected git dir %q, got %q (err: %v)", filepath.Join(repoDir, ".git"), dir, err)
+	}
+}
+
+// unsetRepoEnv unsets the environment variables changing how repositories are found
+// for the duration of the test. git does not treat them as unset when they are empty.
+func unsetRepoEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_CEILING_DIRECTORIES"} {
+		t.Setenv(name, "")
+		if err := os.Unsetenv(name); err != nil {
+			t.Fatalf("failed to unset %s: %v", name, err)
+		}
+	}
+}
+
+// sameFile reports whether the paths name the same file, whatever their symbolic links.
+func sameFile(t *testing.T, a, b string) bool {
+	t.Helper()
+	ia, err := os.Stat(a)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", a, err)
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", b, err)
+	}
+	return os.SameFile(ia, ib)
+}
+
 func TestCheckStagedFiles(t *testing.T) {
 	t.Run("Has staged files", func(t *testing.T) {
 		repoDir := t.TempDir()
@@ -217,6 +579,8 @@ func TestCheckStagedFiles(t *testing.T) {
 }
 
 func TestCommitRepo(t *testing.T) {
+	isolateGitConfig(t)
+
 	tests := []struct {
 		name           string
 		msg            commitMessage
@@ -296,7 +660,7 @@ func TestCommitRepo(t *testing.T) {
 				}
 			}
 
-			commitHash, err := commitRepo(repo, author, &tc.msg)
+			commitHash, err := commitRepo(repo, author, &tc.msg, commitOptions{})
 			if tc.expectErr {
 				if err == nil {
 					t.Errorf("expected error, but got none; commitHash=%q", commitHash)
@@ -341,3 +705,275 @@ func TestCommitRepo(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitRepo_Identities(t *testing.T) {
+	isolateGitConfig(t)
+
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add("file.txt"); err != nil {
+		t.Fatalf("failed to stage file: %v", err)
+	}
+
+	authorDate := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := commitOptions{
+		Committer:  &author{Name: "Committer", Email: "committer@example.com"},
+		AuthorDate: authorDate,
+	}
+	hash, err := commitRepo(repo, author{Name: "Author", Email: "author@example.com"}, &commitMessage{Prefix: "feat", Summary: "pair"}, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	if c.Author.Name != "Author" || c.Author.Email != "author@example.com" {
+		t.Errorf("expected author Author <author@example.com>, got %s <%s>", c.Author.Name, c.Author.Email)
+	}
+	if !c.Author.When.Equal(authorDate) {
+		t.Errorf("expected author date %v, got %v", authorDate, c.Author.When)
+	}
+	if c.Committer.Name != "Committer" || c.Committer.Email != "committer@example.com" {
+		t.Errorf("expected committer Committer <committer@example.com>, got %s <%s>", c.Committer.Name, c.Committer.Email)
+	}
+	if c.Committer.When.Equal(authorDate) {
+		t.Error("expected the committer date to default to the current time")
+	}
+}
+
+func TestCommitRepo_Amend(t *testing.T) {
+	isolateGitConfig(t)
+
+	tests := []struct {
+		name    string
+		commits int
+	}{
+		{name: "RootCommit", commits: 1},
+		{name: "WithParent", commits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			repo, err := git.PlainInit(repoDir, false)
+			if err != nil {
+				t.Fatalf("failed to init repo: %v", err)
+			}
+			wt, err := repo.Worktree()
+			if err != nil {
+				t.Fatalf("failed to get worktree: %v", err)
+			}
+
+			var head plumbing.Hash
+			for i := range tt.commits {
+				name := fmt.Sprintf("file%d.txt", i)
+				if err := os.WriteFile(filepath.Join(repoDir, name), []byte(name), 0644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+				if _, err := wt.Add(name); err != nil {
+					t.Fatalf("failed to stage file: %v", err)
+				}
+				if head, err = wt.Commit("feat: add "+name, &git.CommitOptions{
+					Author: &object.Signature{Name: "Alice", Email: "alice@example.com", When: time.Now()},
+				}); err != nil {
+					t.Fatalf("failed to commit: %v", err)
+				}
+			}
+			old, err := repo.CommitObject(head)
+			if err != nil {
+				t.Fatalf("failed to get commit: %v", err)
+			}
+
+			if runtime.GOOS != "windows" {
+				writeTestHook(t, repoDir, "post-rewrite", "cat > rewritten")
+			}
+
+			a := author{Name: old.Author.Name, Email: old.Author.Email}
+			msg := &commitMessage{Prefix: "fix", Summary: "amended"}
+			hash, err := commitRepo(repo, a, msg, commitOptions{Amend: true, AuthorDate: old.Author.When})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c, err := repo.CommitObject(plumbing.NewHash(hash))
+			if err != nil {
+				t.Fatalf("failed to get commit: %v", err)
+			}
+			if c.Message != "fix: amended" {
+				t.Errorf("expected the amended message, got %q", c.Message)
+			}
+			if c.TreeHash != old.TreeHash {
+				t.Error("expected the tree to be unchanged")
+			}
+			if !slices.Equal(c.ParentHashes, old.ParentHashes) {
+				t.Errorf("expected parents %v, got %v", old.ParentHashes, c.ParentHashes)
+			}
+			if !c.Author.When.Equal(old.Author.When) {
+				t.Errorf("expected author date %v, got %v", old.Author.When, c.Author.When)
+			}
+
+			ref, err := repo.Head()
+			if err != nil {
+				t.Fatalf("failed to get HEAD: %v", err)
+			}
+			if ref.Hash().String() != hash {
+				t.Errorf("expected HEAD to be %s, got %s", hash, ref.Hash())
+			}
+
+			if runtime.GOOS != "windows" {
+				data, err := os.ReadFile(filepath.Join(repoDir, "rewritten"))
+				if err != nil {
+					t.Fatalf("expected the post-rewrite hook to run: %v", err)
+				}
+				if expected := fmt.Sprintf("%s %s\n", old.Hash, hash); string(data) != expected {
+					t.Errorf("expected post-rewrite input %q, got %q", expected, data)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectAuthors(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	authors, err := collectAuthors(repo, 10)
+	if err != nil {
+		t.Fatalf("unexpected error for an empty repository: %v", err)
+	}
+	if len(authors) != 0 {
+		t.Errorf("expected no authors for an empty repository, got %v", authors)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	for i, a := range []author{
+		{Name: "Alice", Email: "alice@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Alice", Email: "alice@example.com"},
+	} {
+		name := fmt.Sprintf("file%d.txt", i)
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage file: %v", err)
+		}
+		if _, err := wt.Commit("chore: add "+name, &git.CommitOptions{
+			Author: &object.Signature{Name: a.Name, Email: a.Email, When: time.Now()},
+		}); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+	}
+
+	authors, err = collectAuthors(repo, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"Alice <alice@example.com>", "Bob <bob@example.com>"}
+	if strings.Join(authors, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected authors %v, got %v", expected, authors)
+	}
+}
+
+func TestCommitsInRange(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("file%d.txt", i)
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage file: %v", err)
+		}
+		h, err := wt.Commit(fmt.Sprintf("feat: add file %d", i), &git.CommitOptions{
+			Author: &object.Signature{Name: "Tester", Email: "tester@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+		hashes = append(hashes, h.String())
+	}
+
+	tests := []struct {
+		name      string
+		spec      string
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "Range",
+			spec:     hashes[0] + "..HEAD",
+			expected: []string{hashes[2], hashes[1]},
+		},
+		{
+			name:     "OpenEnded",
+			spec:     "HEAD~1..",
+			expected: []string{hashes[2]},
+		},
+		{
+			name:     "SingleRevision",
+			spec:     "HEAD~1",
+			expected: []string{hashes[1]},
+		},
+		{
+			name:      "UnknownRevision",
+			spec:      "nope..HEAD",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := commitsInRange(repo, tt.spec)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, c := range commits {
+				got = append(got, c.Hash.String())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected commits %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
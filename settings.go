@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// repoSettingsFiles lists the file names checked at the repository root, in order of precedence.
+var repoSettingsFiles = []string{".git-cm.toml", ".git-cm.yaml", ".git-cm.yml"}
+
+// userSettingsFiles lists the file names checked in the user-level git-cm config directory.
+var userSettingsFiles = []string{"config.toml", "config.yaml", "config.yml"}
+
+// prefixOption is a commit type offered in the Prefix dropdown.
+type prefixOption struct {
+	Name        string `toml:"name" yaml:"name"`
+	Description string `toml:"description" yaml:"description"`
+}
+
+// settings holds the git-cm configuration.
+// Zero values mean "not set" so that a repository file only overrides the keys it defines.
+type settings struct {
+	Prefixes      []prefixOption `toml:"prefixes" yaml:"prefixes"`
+	Scopes        []string       `toml:"scopes" yaml:"scopes"`
+	DefaultPrefix string         `toml:"default_prefix" yaml:"default_prefix"`
+	SummaryLimit  int            `toml:"summary_limit" yaml:"summary_limit"`
+	Suggest       []suggestRule  `toml:"suggest" yaml:"suggest"`
+	Ticket        ticketConfig   `toml:"ticket" yaml:"ticket"`
+
+	Templates       []commitTemplate `toml:"templates" yaml:"templates"`
+	DefaultTemplate string           `toml:"default_template" yaml:"default_template"`
+
+	Lint map[string]lintRuleConfig `toml:"lint" yaml:"lint"`
+}
+
+// defaultSettings returns the built-in configuration used when no config file is present.
+func defaultSettings() *settings {
+	return &settings{
+		Prefixes: []prefixOption{
+			{Name: "feat", Description: "A new feature"},
+			{Name: "fix", Description: "A bug fix"},
+			{Name: "refactor", Description: "A code change that neither fixes a bug nor adds a feature"},
+			{Name: "test", Description: "Adding missing or correcting existing tests"},
+			{Name: "style", Description: "Changes that do not affect the meaning of the code"},
+			{Name: "chore", Description: "Changes to the build process or auxiliary tools"},
+			{Name: "docs", Description: "Documentation only changes"},
+		},
+		DefaultPrefix: "feat",
+		SummaryLimit:  100,
+		Ticket: ticketConfig{
+			Pattern: defaultTicketPattern,
+			Insert:  ticketInsertTrailer,
+			Trailer: "Refs",
+		},
+	}
+}
+
+// userSettingsDir returns the directory holding the user-level git-cm configuration,
+// i.e. $XDG_CONFIG_HOME/git-cm, falling back to ~/.config/git-cm.
+func userSettingsDir() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "git-cm"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "git-cm"), nil
+}
+
+// findSettingsFile returns the path of the first file in names that exists in dir,
+// or an empty string if none of them exist.
+func findSettingsFile(dir string, names []string) string {
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+	}
+	return ""
+}
+
+// readSettingsFile decodes a TOML or YAML config file, chosen by its extension.
+func readSettingsFile(path string) (*settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	s := &settings{}
+	switch filepath.Ext(path) {
+	case ".toml":
+		if err := toml.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+	return s, nil
+}
+
+// merge overrides the receiver with every key that is set in o.
+func (s *settings) merge(o *settings) {
+	if len(o.Prefixes) > 0 {
+		s.Prefixes = o.Prefixes
+		// An inherited default that is not in the new list falls back to its first prefix;
+		// only a default set along with the list must be one of them.
+		if o.DefaultPrefix == "" && s.prefixIndex(s.DefaultPrefix) < 0 {
+			s.DefaultPrefix = o.Prefixes[0].Name
+		}
+	}
+	if len(o.Scopes) > 0 {
+		s.Scopes = o.Scopes
+	}
+	if o.DefaultPrefix != "" {
+		s.DefaultPrefix = o.DefaultPrefix
+	}
+	if o.SummaryLimit > 0 {
+		s.SummaryLimit = o.SummaryLimit
+	}
+	if len(o.Suggest) > 0 {
+		s.Suggest = o.Suggest
+	}
+	s.Ticket.merge(o.Ticket)
+	if len(o.Templates) > 0 {
+		s.Templates = o.Templates
+	}
+	if o.DefaultTemplate != "" {
+		s.DefaultTemplate = o.DefaultTemplate
+	}
+	for name, rc := range o.Lint {
+		if s.Lint == nil {
+			s.Lint = make(map[string]lintRuleConfig)
+		}
+		merged := s.Lint[name]
+		merged.merge(rc)
+		s.Lint[name] = merged
+	}
+}
+
+// validate checks that the merged configuration is usable by the TUI.
+func (s *settings) validate() error {
+	for _, p := range s.Prefixes {
+		if p.Name == "" {
+			return fmt.Errorf("prefix name must not be empty")
+		}
+	}
+
+	if s.prefixIndex(s.DefaultPrefix) < 0 {
+		return fmt.Errorf("default prefix %q is not in the prefix list", s.DefaultPrefix)
+	}
+
+	for _, r := range s.Suggest {
+		if err := r.validate(); err != nil {
+			return err
+		}
+		if r.Prefix != "" && s.prefixIndex(r.Prefix) < 0 {
+			return fmt.Errorf("suggested prefix %q is not in the prefix list", r.Prefix)
+		}
+	}
+	if err := s.Ticket.validate(); err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, t := range s.Templates {
+		switch {
+		case t.Name == "":
+			return fmt.Errorf("template name must not be empty")
+		case t.Name == gitTemplateName:
+			return fmt.Errorf("template name %q is reserved for the commit.template file", t.Name)
+		case names[t.Name]:
+			return fmt.Errorf("duplicate template %q", t.Name)
+		}
+		names[t.Name] = true
+	}
+	// commit.template is only known once the git config is read.
+	if s.DefaultTemplate != "" && s.DefaultTemplate != gitTemplateName && !names[s.DefaultTemplate] {
+		return fmt.Errorf("default template %q is not in the template list", s.DefaultTemplate)
+	}
+	return validateLintConfig(s.Lint)
+}
+
+// prefixIndex returns the position of the named prefix, or -1 if it is not configured.
+func (s *settings) prefixIndex(name string) int {
+	for i, p := range s.Prefixes {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// settingsFiles returns the config files read for the repository at root, from the lowest
+// precedence: the user-level file and the repository file, when they exist.
+func settingsFiles(root string) ([]string, error) {
+	userDir, err := userSettingsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, p := range []string{
+		findSettingsFile(userDir, userSettingsFiles),
+		findSettingsFile(root, repoSettingsFiles),
+	} {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	return files, nil
+}
+
+// loadSettings builds the configuration for the repository at root by layering
+// the built-in defaults, the user-level config file and the repository config file.
+func loadSettings(root string) (*settings, error) {
+	s := defaultSettings()
+
+	files, err := settingsFiles(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range files {
+		fileSettings, err := readSettingsFile(p)
+		if err != nil {
+			return nil, err
+		}
+		s.merge(fileSettings)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return s, nil
+}
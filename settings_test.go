@@ -0,0 +1,248 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSettingsFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		fileName      string
+		content       string
+		expectedNames []string
+		expectedLimit int
+		expectErr     bool
+	}{
+		{
+			name:     "TOML",
+			fileName: ".git-cm.toml",
+			content: `default_prefix = "perf"
+summary_limit = 72
+
+[[prefixes]]
+name = "perf"
+description = "A performance improvement"
+
+[[prefixes]]
+name = "ci"
+description = "CI configuration changes"
+`,
+			expectedNames: []string{"perf", "ci"},
+			expectedLimit: 72,
+		},
+		{
+			name:     "YAML",
+			fileName: ".git-cm.yaml",
+			content: `default_prefix: build
+summary_limit: 60
+prefixes:
+  - name: build
+    description: Build system changes
+`,
+			expectedNames: []string{"build"},
+			expectedLimit: 60,
+		},
+		{
+			name:      "InvalidTOML",
+			fileName:  ".git-cm.toml",
+			content:   "prefixes = [",
+			expectErr: true,
+		},
+		{
+			name:      "UnsupportedExtension",
+			fileName:  ".git-cm.json",
+			content:   "{}",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(p, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			s, err := readSettingsFile(p)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(s.Prefixes) != len(tt.expectedNames) {
+				t.Fatalf("expected %d prefixes, got %d", len(tt.expectedNames), len(s.Prefixes))
+			}
+			for i, name := range tt.expectedNames {
+				if s.Prefixes[i].Name != name {
+					t.Errorf("expected prefix %q at %d, got %q", name, i, s.Prefixes[i].Name)
+				}
+			}
+
+			if s.SummaryLimit != tt.expectedLimit {
+				t.Errorf("expected summary limit %d, got %d", tt.expectedLimit, s.SummaryLimit)
+			}
+		})
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		userContent    string
+		repoContent    string
+		expectedPrefix string
+		expectedLimit  int
+		expectedCount  int
+		expectErr      bool
+	}{
+		{
+			name:           "Defaults",
+			expectedPrefix: "feat",
+			expectedLimit:  100,
+			expectedCount:  7,
+		},
+		{
+			name:           "UserOnly",
+			userContent:    "summary_limit = 80\n",
+			expectedPrefix: "feat",
+			expectedLimit:  80,
+			expectedCount:  7,
+		},
+		{
+			name:        "RepoOverridesUser",
+			userContent: "summary_limit = 80\ndefault_prefix = \"fix\"\n",
+			repoContent: `default_prefix = "perf"
+
+[[prefixes]]
+name = "feat"
+
+[[prefixes]]
+name = "perf"
+`,
+			expectedPrefix: "perf",
+			expectedLimit:  80,
+			expectedCount:  2,
+		},
+		{
+			name:        "UnknownSuggestedPrefix",
+			repoContent: "[[suggest]]\npaths = [\"*.go\"]\nprefix = \"perf\"\n",
+			expectErr:   true,
+		},
+		{
+			name:        "SuggestRuleWithoutPaths",
+			repoContent: "[[suggest]]\nscope = \"api\"\n",
+			expectErr:   true,
+		},
+		{
+			name:        "DuplicateTemplate",
+			repoContent: "[[templates]]\nname = \"a\"\n\n[[templates]]\nname = \"a\"\n",
+			expectErr:   true,
+		},
+		{
+			name:        "UnknownDefaultTemplate",
+			repoContent: "default_template = \"feature\"\n",
+			expectErr:   true,
+		},
+		{
+			name:           "DefaultGitTemplate",
+			repoContent:    "default_template = \"commit.template\"\n",
+			expectedPrefix: "feat",
+			expectedLimit:  100,
+			expectedCount:  7,
+		},
+		{
+			name:        "UnknownDefaultPrefix",
+			repoContent: "default_prefix = \"unknown\"\n",
+			expectErr:   true,
+		},
+		{
+			name:           "PrefixesWithoutDefault",
+			repoContent:    "[[prefixes]]\nname = \"perf\"\n\n[[prefixes]]\nname = \"build\"\n",
+			expectedPrefix: "perf",
+			expectedLimit:  100,
+			expectedCount:  2,
+		},
+		{
+			name:           "UserDefaultNotInRepoPrefixes",
+			userContent:    "default_prefix = \"fix\"\n",
+			repoContent:    "[[prefixes]]\nname = \"perf\"\n\n[[prefixes]]\nname = \"build\"\n",
+			expectedPrefix: "perf",
+			expectedLimit:  100,
+			expectedCount:  2,
+		},
+		{
+			name:        "DefaultNotInPrefixes",
+			repoContent: "default_prefix = \"fix\"\n\n[[prefixes]]\nname = \"perf\"\n",
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xdgDir := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", xdgDir)
+
+			if tt.userContent != "" {
+				userDir := filepath.Join(xdgDir, "git-cm")
+				if err := os.MkdirAll(userDir, 0755); err != nil {
+					t.Fatalf("failed to create user config directory: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(userDir, "config.toml"), []byte(tt.userContent), 0644); err != nil {
+					t.Fatalf("failed to write user config: %v", err)
+				}
+			}
+
+			root := t.TempDir()
+			if tt.repoContent != "" {
+				if err := os.WriteFile(filepath.Join(root, ".git-cm.toml"), []byte(tt.repoContent), 0644); err != nil {
+					t.Fatalf("failed to write repository config: %v", err)
+				}
+			}
+
+			s, err := loadSettings(root)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if s.DefaultPrefix != tt.expectedPrefix {
+				t.Errorf("expected default prefix %q, got %q", tt.expectedPrefix, s.DefaultPrefix)
+			}
+			if s.SummaryLimit != tt.expectedLimit {
+				t.Errorf("expected summary limit %d, got %d", tt.expectedLimit, s.SummaryLimit)
+			}
+			if len(s.Prefixes) != tt.expectedCount {
+				t.Errorf("expected %d prefixes, got %d", tt.expectedCount, len(s.Prefixes))
+			}
+		})
+	}
+}
+
+func TestSettingsMerge_Lint(t *testing.T) {
+	s := defaultSettings()
+	s.merge(&settings{Lint: map[string]lintRuleConfig{
+		"header-max-length": {Severity: severityWarning, Value: 72},
+	}})
+	s.merge(&settings{Lint: map[string]lintRuleConfig{
+		"header-max-length": {Value: 50},
+	}})
+
+	rc := s.Lint["header-max-length"]
+	if rc.Severity != severityWarning || rc.Value != 50 {
+		t.Errorf("expected merged rule {warning 50}, got %+v", rc)
+	}
+}
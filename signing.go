@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// programSigner signs commits by running an external program the way git does: the commit is
+// passed on standard input and the program writes the armored signature to standard output.
+// It implements git.Signer.
+type programSigner struct {
+	program string
+	args    []string
+	// sshKey holds a literal SSH public key ("key::ssh-ed25519 AAAA..." in user.signingkey),
+	// which is written to a temporary file for ssh-keygen -f at signing time. Like git, -U is
+	// passed so that ssh-keygen signs with the private key held by ssh-agent.
+	sshKey string
+}
+
+// Sign signs the encoded commit read from message.
+func (s *programSigner) Sign(message io.Reader) ([]byte, error) {
+	args := s.args
+	if s.sshKey != "" {
+		f, err := os.CreateTemp("", "git-cm-signingkey-*.pub")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create signing key file: %w", err)
+		}
+		defer os.Remove(f.Name()) // nolint:errcheck
+
+		_, err = f.WriteString(s.sshKey + "\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write signing key file: %w", err)
+		}
+		args = append(args, f.Name(), "-U")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.program, args...)
+	cmd.Stdin = message
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed to sign the data: %w: %s", s.program, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("%s did not write a signature", s.program)
+	}
+	return stdout.Bytes(), nil
+}
+
+// gpgSignEnabled reports whether commit.gpgsign asks for every commit to be signed.
+func gpgSignEnabled(repo *git.Repository) (bool, error) {
+	v, err := gitConfigValue(repo, "commit", "", "gpgsign")
+	if err != nil {
+		return false, err
+	}
+
+	sign, err := parseGitBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid commit.gpgsign: %w", err)
+	}
+	return sign, nil
+}
+
+// newCommitSigner returns the signer configured by gpg.format and user.signingkey:
+//
+//   - openpgp (the default) runs gpg.openpgp.program or gpg.program ("gpg"), with the committer
+//     identity as the key when user.signingkey is not set;
+//   - x509 runs gpg.x509.program ("gpgsm");
+//   - ssh runs gpg.ssh.program ("ssh-keygen") with user.signingkey, which is either the path of a
+//     key file or a literal public key ("key::ssh-ed25519 AAAA...") whose private key is in ssh-agent.
+func newCommitSigner(repo *git.Repository, committer author) (git.Signer, error) {
+	format, err := gitConfigValue(repo, "gpg", "", "format")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := gitConfigValue(repo, "user", "", "signingkey")
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "", "openpgp":
+		program, err := signingProgram(repo, "openpgp", "")
+		if err != nil {
+			return nil, err
+		}
+		if program == "" {
+			// gpg.program is the historical name of gpg.openpgp.program.
+			if program, err = signingProgram(repo, "", "gpg"); err != nil {
+				return nil, err
+			}
+		}
+		if key == "" {
+			key = committer.String()
+		}
+		return &programSigner{program: program, args: []string{"--status-fd=2", "-bsau", key}}, nil
+
+	case "x509":
+		program, err := signingProgram(repo, "x509", "gpgsm")
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			key = committer.String()
+		}
+		return &programSigner{program: program, args: []string{"--status-fd=2", "-bsau", key}}, nil
+
+	case "ssh":
+		program, err := signingProgram(repo, "ssh", "ssh-keygen")
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return nil, fmt.Errorf("user.signingkey must be set to sign commits with gpg.format=ssh")
+		}
+
+		s := &programSigner{program: program, args: []string{"-Y", "sign", "-n", "git", "-f"}}
+		switch {
+		case strings.HasPrefix(key, "key::"):
+			s.sshKey = strings.TrimPrefix(key, "key::")
+		case strings.HasPrefix(key, "ssh-"):
+			// Deprecated by git in favor of the key:: prefix, but still accepted.
+			s.sshKey = key
+		default:
+			p, err := expandHomeDir(key)
+			if err != nil {
+				return nil, err
+			}
+			s.args = append(s.args, p)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported gpg.format %q", format)
+}
+
+// signingProgram returns the program option of the given gpg subsection (gpg.<format>.program,
+// or gpg.program when subsection is empty), or def when it is not set.
+func signingProgram(repo *git.Repository, subsection, def string) (string, error) {
+	p, err := gitConfigValue(repo, "gpg", subsection, "program")
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return def, nil
+	}
+	return p, nil
+}
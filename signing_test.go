@@ -0,0 +1,251 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// setRepoConfig sets "section.key" or "section.subsection.key" options in the repository config.
+func setRepoConfig(t *testing.T, repo *git.Repository, options map[string]string) {
+	t.Helper()
+	cfg, err := repo.Config()
+	if err != nil {
+		t.Fatalf("failed to get repository config: %v", err)
+	}
+	for name, value := range options {
+		parts := strings.Split(name, ".")
+		s := cfg.Raw.Section(parts[0])
+		if len(parts) == 3 {
+			s.Subsection(parts[1]).SetOption(parts[2], value)
+		} else {
+			s.SetOption(parts[1], value)
+		}
+	}
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		t.Fatalf("failed to set repository config: %v", err)
+	}
+}
+
+func TestNewCommitSigner(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          map[string]string
+		expectedProgram string
+		expectedArgs    []string
+		expectedSSHKey  string
+		expectErr       bool
+	}{
+		{
+			name:            "OpenPGPDefaultKey",
+			expectedProgram: "gpg",
+			expectedArgs:    []string{"--status-fd=2", "-bsau", "Tester <tester@example.com>"},
+		},
+		{
+			name:            "OpenPGPProgram",
+			config:          map[string]string{"user.signingkey": "ABCD1234", "gpg.program": "gpg2"},
+			expectedProgram: "gpg2",
+			expectedArgs:    []string{"--status-fd=2", "-bsau", "ABCD1234"},
+		},
+		{
+			name:            "OpenPGPProgramOverridesLegacy",
+			config:          map[string]string{"gpg.format": "openpgp", "gpg.program": "gpg2", "gpg.openpgp.program": "gpg3"},
+			expectedProgram: "gpg3",
+			expectedArgs:    []string{"--status-fd=2", "-bsau", "Tester <tester@example.com>"},
+		},
+		{
+			name:            "X509",
+			config:          map[string]string{"gpg.format": "x509"},
+			expectedProgram: "gpgsm",
+			expectedArgs:    []string{"--status-fd=2", "-bsau", "Tester <tester@example.com>"},
+		},
+		{
+			name:            "SSHKeyFile",
+			config:          map[string]string{"gpg.format": "ssh", "user.signingkey": "/keys/id_ed25519.pub"},
+			expectedProgram: "ssh-keygen",
+			expectedArgs:    []string{"-Y", "sign", "-n", "git", "-f", "/keys/id_ed25519.pub"},
+		},
+		{
+			name:            "SSHLiteralKey",
+			config:          map[string]string{"gpg.format": "ssh", "user.signingkey": "key::ssh-ed25519 AAAA", "gpg.ssh.program": "/usr/bin/ssh-keygen"},
+			expectedProgram: "/usr/bin/ssh-keygen",
+			expectedArgs:    []string{"-Y", "sign", "-n", "git", "-f"},
+			expectedSSHKey:  "ssh-ed25519 AAAA",
+		},
+		{
+			name:      "SSHWithoutKey",
+			config:    map[string]string{"gpg.format": "ssh"},
+			expectErr: true,
+		},
+		{
+			name:      "UnsupportedFormat",
+			config:    map[string]string{"gpg.format": "pgp"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", "")
+
+			repo, err := git.PlainInit(t.TempDir(), false)
+			if err != nil {
+				t.Fatalf("failed to init repo: %v", err)
+			}
+			setRepoConfig(t, repo, tt.config)
+
+			signer, err := newCommitSigner(repo, author{Name: "Tester", Email: "tester@example.com"})
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			s, ok := signer.(*programSigner)
+			if !ok {
+				t.Fatalf("expected *programSigner, got %T", signer)
+			}
+			if s.program != tt.expectedProgram {
+				t.Errorf("expected program %q, got %q", tt.expectedProgram, s.program)
+			}
+			if !slices.Equal(s.args, tt.expectedArgs) {
+				t.Errorf("expected args %q, got %q", tt.expectedArgs, s.args)
+			}
+			if s.sshKey != tt.expectedSSHKey {
+				t.Errorf("expected SSH key %q, got %q", tt.expectedSSHKey, s.sshKey)
+			}
+		})
+	}
+}
+
+func TestProgramSigner_SSHLiteralKey(t *testing.T) {
+	// The fake ssh-keygen saves its arguments, one per line, and prints the key file as the signature.
+	dir := t.TempDir()
+	program := filepath.Join(dir, "ssh-keygen")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + filepath.Join(dir, "args") + "\nprev=\nfor a in \"$@\"; do [ \"$prev\" = -f ] && cat \"$a\"; prev=$a; done\n"
+	if err := os.WriteFile(program, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write program: %v", err)
+	}
+
+	s := &programSigner{program: program, args: []string{"-Y", "sign", "-n", "git", "-f"}, sshKey: "ssh-ed25519 AAAA"}
+	sig, err := s.Sign(strings.NewReader("commit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(sig) != "ssh-ed25519 AAAA\n" {
+		t.Errorf("expected the key file to hold the key, got %q", sig)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatalf("failed to read arguments: %v", err)
+	}
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(args) != 7 || !slices.Equal(args[:5], s.args) || args[6] != "-U" {
+		t.Errorf("expected -f KEYFILE -U, got %q", args)
+	}
+}
+
+func TestGPGSignEnabled(t *testing.T) {
+	tests := []struct {
+		value     string
+		expected  bool
+		expectErr bool
+	}{
+		{value: "", expected: false},
+		{value: "true", expected: true},
+		{value: "Yes", expected: true},
+		{value: "off", expected: false},
+		{value: "maybe", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("XDG_CONFIG_HOME", "")
+
+			repo, err := git.PlainInit(t.TempDir(), false)
+			if err != nil {
+				t.Fatalf("failed to init repo: %v", err)
+			}
+			if tt.value != "" {
+				setRepoConfig(t, repo, map[string]string{"commit.gpgsign": tt.value})
+			}
+
+			sign, err := gpgSignEnabled(repo)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sign != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, sign)
+			}
+		})
+	}
+}
+
+func TestCommitRepo_SSHSigned(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate SSH key: %v: %s", err, out)
+	}
+
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	setRepoConfig(t, repo, map[string]string{"gpg.format": "ssh", "user.signingkey": keyPath})
+
+	if err := os.WriteFile(filepath.Join(root, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	if _, err := wt.Add("file.txt"); err != nil {
+		t.Fatalf("failed to stage file: %v", err)
+	}
+
+	a := author{Name: "Tester", Email: "tester@example.com"}
+	signer, err := newCommitSigner(repo, a)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hash, err := commitRepo(repo, a, &commitMessage{Prefix: "feat", Summary: "sign commits"}, commitOptions{Signer: signer})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		t.Fatalf("failed to get commit: %v", err)
+	}
+	if !strings.HasPrefix(c.PGPSignature, "-----BEGIN SSH SIGNATURE-----") {
+		t.Errorf("expected an SSH signature, got %q", c.PGPSignature)
+	}
+}
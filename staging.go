@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// fileStatus is the status of a changed file, as shown by `git status --short`:
+// Staging compares the index with HEAD and Worktree compares the worktree with the index.
+type fileStatus struct {
+	Path     string
+	Staging  git.StatusCode
+	Worktree git.StatusCode
+}
+
+// staged reports whether the file has changes in the index.
+func (f fileStatus) staged() bool {
+	return f.Staging != git.Unmodified && f.Staging != git.Untracked
+}
+
+// unstaged reports whether the file has changes in the worktree that are not staged,
+// or is untracked.
+func (f fileStatus) unstaged() bool {
+	return f.Worktree != git.Unmodified
+}
+
+// stagingArea stages and unstages the changes of a repository's worktree.
+type stagingArea struct {
+	repo *git.Repository
+	wt   *git.Worktree
+}
+
+// newStagingArea returns the stagingArea of the repository's worktree.
+func newStagingArea(repo *git.Repository) (*stagingArea, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	return &stagingArea{repo: repo, wt: wt}, nil
+}
+
+// files returns the files with staged or unstaged changes, sorted by path.
+func (s *stagingArea) files() ([]fileStatus, error) {
+	status, err := s.wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	var files []fileStatus
+	for path, fs := range status {
+		f := fileStatus{Path: path, Staging: fs.Staging, Worktree: fs.Worktree}
+		if f.staged() || f.unstaged() {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// stage stages the worktree version of the file, including its deletion, like `git add`.
+func (s *stagingArea) stage(path string) error {
+	if _, err := s.wt.Add(path); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+	return nil
+}
+
+// unstage restores the index entry of the file to its HEAD version, like `git restore --staged`.
+// Before the first commit, it removes the file from the index.
+func (s *stagingArea) unstage(path string) error {
+	if _, err := s.repo.Head(); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		idx, err := s.repo.Storer.Index()
+		if err != nil {
+			return fmt.Errorf("failed to read index: %w", err)
+		}
+		if _, err := idx.Remove(path); err != nil {
+			return fmt.Errorf("failed to unstage %s: %w", path, err)
+		}
+		return s.repo.Storer.SetIndex(idx)
+	}
+
+	if err := s.wt.Restore(&git.RestoreOptions{Staged: true, Files: []string{path}}); err != nil {
+		return fmt.Errorf("failed to unstage %s: %w", path, err)
+	}
+	return nil
+}
+
+// diff returns the diff of the file from its index version to its worktree version, i.e. the
+// unstaged changes. An untracked file is diffed against an empty file.
+func (s *stagingArea) diff(path string) (*fileDiff, error) {
+	staged, err := s.indexContent(path)
+	if err != nil {
+		return nil, err
+	}
+	content, _, err := s.worktreeContent(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := diffFile(staged, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// stagedFileDiff is the staged diff of a file; Diff is nil for a binary file.
+type stagedFileDiff struct {
+	Path string
+	Diff *fileDiff
+}
+
+// stagedDiff returns the diffs of the staged files from HEAD to the index, like
+// `git diff --cached`, sorted by path.
+func (s *stagingArea) stagedDiff() ([]stagedFileDiff, error) {
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var tree *object.Tree
+	if head, err := s.repo.Head(); err == nil {
+		c, err := s.repo.CommitObject(head.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+		}
+		if tree, err = c.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to get HEAD tree: %w", err)
+		}
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	var diffs []stagedFileDiff
+	for _, f := range files {
+		if !f.staged() {
+			continue
+		}
+
+		var committed, staged []byte
+		if tree != nil {
+			if committed, err = treeContent(tree, f.Path); err != nil {
+				return nil, err
+			}
+		}
+		if staged, err = s.indexContent(f.Path); err != nil {
+			return nil, err
+		}
+
+		fd := stagedFileDiff{Path: f.Path}
+		if !isBinary(committed) && !isBinary(staged) {
+			if fd.Diff, err = diffFile(committed, staged); err != nil {
+				return nil, err
+			}
+		}
+		diffs = append(diffs, fd)
+	}
+	return diffs, nil
+}
+
+// treeContent returns the content of the file in the tree, or nil when it is not in the tree.
+func treeContent(tree *object.Tree, path string) ([]byte, error) {
+	f, err := tree.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read the committed %s: %w", path, err)
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the committed %s: %w", path, err)
+	}
+	defer r.Close() // nolint:errcheck
+	return io.ReadAll(r)
+}
+
+// stageLines stages the selected lines of a diff returned by diff, like `git add -p`:
+// it writes the index version with the selected changes applied to the object storage and
+// points the index entry of the file to it.
+func (s *stagingArea) stageLines(path string, d *fileDiff, selected map[int]bool) error {
+	_, mode, err := s.worktreeContent(path)
+	if err != nil {
+		return err
+	}
+
+	content := d.apply(selected)
+	obj := s.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	_, err = w.Write(content)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write blob: %w", err)
+	}
+	hash, err := s.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	idx, err := s.repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	e, err := idx.Entry(path)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		e = idx.Add(path)
+	} else if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	e.Hash = hash
+	e.Mode = mode
+	e.Size = uint32(len(content))
+	if err := s.repo.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+	return nil
+}
+
+// indexContent returns the content of the file in the index, or nil when it is not in the index.
+func (s *stagingArea) indexContent(path string) ([]byte, error) {
+	idx, err := s.repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	e, err := idx.Entry(path)
+	if errors.Is(err, index.ErrEntryNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	blob, err := s.repo.BlobObject(e.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the staged %s: %w", path, err)
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the staged %s: %w", path, err)
+	}
+	defer r.Close() // nolint:errcheck
+	return io.ReadAll(r)
+}
+
+// worktreeContent returns the content and the mode of a regular file in the worktree.
+func (s *stagingArea) worktreeContent(path string) ([]byte, filemode.FileMode, error) {
+	fs := s.wt.Filesystem
+	fi, err := fs.Lstat(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s cannot be staged by hunk: %w", path, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, 0, fmt.Errorf("%s cannot be staged by hunk: not a regular file", path)
+	}
+	mode, err := filemode.NewFromOSFileMode(fi.Mode())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close() // nolint:errcheck
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return content, mode, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newTestStagingArea returns the stagingArea of a new repository whose files are created
+// from committed and then updated with worktree; an empty content deletes the file.
+func newTestStagingArea(t *testing.T, committed, worktree map[string]string) (*stagingArea, string) {
+	t.Helper()
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	s, err := newStagingArea(repo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(committed) > 0 {
+		for name, content := range committed {
+			if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+			if _, err := s.wt.Add(name); err != nil {
+				t.Fatalf("failed to stage %s: %v", name, err)
+			}
+		}
+		if _, err := s.wt.Commit("chore: initial commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "Tester", Email: "tester@example.com", When: time.Now()},
+		}); err != nil {
+			t.Fatalf("failed to commit: %v", err)
+		}
+	}
+
+	for name, content := range worktree {
+		p := filepath.Join(root, name)
+		if content == "" {
+			err = os.Remove(p)
+		} else {
+			err = os.WriteFile(p, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatalf("failed to update %s: %v", name, err)
+		}
+	}
+	return s, root
+}
+
+// fileCodes returns the "XY" status codes of the files, keyed by path.
+func fileCodes(t *testing.T, s *stagingArea) map[string]string {
+	t.Helper()
+	files, err := s.files()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	codes := make(map[string]string, len(files))
+	for _, f := range files {
+		codes[f.Path] = string(f.Staging) + string(f.Worktree)
+	}
+	return codes
+}
+
+func TestStagingArea(t *testing.T) {
+	s, _ := newTestStagingArea(t,
+		map[string]string{"modified.txt": "old\n", "deleted.txt": "gone\n", "clean.txt": "clean\n"},
+		map[string]string{"modified.txt": "new\n", "deleted.txt": "", "new.txt": "new\n"},
+	)
+
+	expected := map[string]string{"modified.txt": " M", "deleted.txt": " D", "new.txt": "??"}
+	if got := fileCodes(t, s); !maps.Equal(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	for _, path := range []string{"modified.txt", "deleted.txt", "new.txt"} {
+		if err := s.stage(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected = map[string]string{"modified.txt": "M ", "deleted.txt": "D ", "new.txt": "A "}
+	if got := fileCodes(t, s); !maps.Equal(got, expected) {
+		t.Fatalf("expected %v after staging, got %v", expected, got)
+	}
+
+	for _, path := range []string{"modified.txt", "deleted.txt", "new.txt"} {
+		if err := s.unstage(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected = map[string]string{"modified.txt": " M", "deleted.txt": " D", "new.txt": "??"}
+	if got := fileCodes(t, s); !maps.Equal(got, expected) {
+		t.Errorf("expected %v after unstaging, got %v", expected, got)
+	}
+}
+
+func TestStagingArea_UnstageBeforeFirstCommit(t *testing.T) {
+	s, _ := newTestStagingArea(t, nil, map[string]string{"new.txt": "new\n"})
+
+	if err := s.stage("new.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.unstage("new.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"new.txt": "??"}
+	if got := fileCodes(t, s); !maps.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestStagingArea_StageLines(t *testing.T) {
+	s, root := newTestStagingArea(t,
+		map[string]string{"file.txt": numberedLines(20, nil)},
+		map[string]string{"file.txt": numberedLines(20, map[int]string{2: "two\n", 18: "eighteen\n"}), "new.txt": "a\nb\n"},
+	)
+
+	// Stage the second hunk only.
+	d, err := s.diff("file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(d.Hunks))
+	}
+	selected := map[int]bool{}
+	for i := d.Hunks[1].Start; i < d.Hunks[1].End; i++ {
+		selected[i] = d.Lines[i].changed()
+	}
+	if err := s.stageLines("file.txt", d, selected); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	staged, err := s.indexContent("file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := numberedLines(20, map[int]string{18: "eighteen\n"}); string(staged) != expected {
+		t.Errorf("expected staged content %q, got %q", expected, staged)
+	}
+
+	// The remaining change is still unstaged.
+	d, err = s.diff("file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Hunks) != 1 || d.Hunks[0].header() != "@@ -1,5 +1,5 @@" {
+		t.Errorf("expected the first hunk to remain, got %+v", d.Hunks)
+	}
+
+	// Stage the first line of an untracked file.
+	d, err = s.diff("new.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.stageLines("new.txt", d, map[int]bool{0: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"file.txt": "MM", "new.txt": "AM"}
+	if got := fileCodes(t, s); !maps.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// The worktree is left untouched.
+	content, err := os.ReadFile(filepath.Join(root, "new.txt"))
+	if err != nil {
+		t.Fatalf("failed to read new.txt: %v", err)
+	}
+	if string(content) != "a\nb\n" {
+		t.Errorf("expected the worktree file to be unchanged, got %q", content)
+	}
+}
+
+func TestStagingArea_StagedDiff(t *testing.T) {
+	s, _ := newTestStagingArea(t,
+		map[string]string{"modified.txt": "a\nb\n", "deleted.txt": "gone\n", "unstaged.txt": "old\n"},
+		map[string]string{"modified.txt": "a\nB\nc\n", "deleted.txt": "", "new.bin": "\x00\x01", "unstaged.txt": "new\n"},
+	)
+	for _, path := range []string{"modified.txt", "deleted.txt", "new.bin"} {
+		if err := s.stage(path); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	diffs, err := s.stagedDiff()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var paths []string
+	var stats diffStats
+	for _, fd := range diffs {
+		paths = append(paths, fd.Path)
+		stats.add(fd.Diff)
+	}
+	if expected := []string{"deleted.txt", "modified.txt", "new.bin"}; !slices.Equal(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+	if expected := (diffStats{Files: 3, Insertions: 2, Deletions: 2}); stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+	if diffs[2].Diff != nil {
+		t.Error("expected no line diff for a binary file")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// suggestRule suggests a prefix and/or a scope when every staged path matches one of its globs.
+// A glob without a slash matches the file name in any directory, like in .gitignore;
+// "**" matches any number of directories.
+type suggestRule struct {
+	Paths  []string `toml:"paths" yaml:"paths"`
+	Prefix string   `toml:"prefix" yaml:"prefix"`
+	Scope  string   `toml:"scope" yaml:"scope"`
+}
+
+// defaultSuggestRules are the built-in rules, checked after the configured ones.
+// A suggested prefix that is not configured is ignored.
+var defaultSuggestRules = []suggestRule{
+	{Paths: []string{"*_test.go"}, Prefix: "test"},
+	{Paths: []string{"*.md", "docs/**", "doc/**"}, Prefix: "docs"},
+	{
+		Paths: []string{
+			".github/workflows/**", ".gitlab-ci.yml", ".circleci/**", ".travis.yml",
+			"Jenkinsfile", "azure-pipelines.yml",
+		},
+		Prefix: "ci",
+	},
+}
+
+// validate checks that the rule can match and suggests something.
+func (r suggestRule) validate() error {
+	if len(r.Paths) == 0 {
+		return fmt.Errorf("suggest rule must have paths")
+	}
+	if r.Prefix == "" && r.Scope == "" {
+		return fmt.Errorf("suggest rule for %q must have a prefix or a scope", r.Paths)
+	}
+	return nil
+}
+
+// matches reports whether every path matches one of the rule's globs.
+func (r suggestRule) matches(paths []string) bool {
+	if len(paths) == 0 {
+		return false
+	}
+
+	var patterns []*regexp.Regexp
+	for _, p := range r.Paths {
+		patterns = append(patterns, regexp.MustCompile(globPattern(p)))
+	}
+	for _, p := range paths {
+		matched := false
+		for i, re := range patterns {
+			name := p
+			if !strings.Contains(r.Paths[i], "/") {
+				name = path.Base(p)
+			}
+			if re.MatchString(name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// suggest returns the prefix and the scope inferred from the staged paths: the first matching
+// rule with a configured prefix gives the prefix, and the first matching rule with a scope gives
+// the scope. Without a matching scope rule, the top-level directory shared by all the paths is
+// suggested, unless it is hidden or it is not one of the scopes when scopes are configured.
+// Empty strings mean no suggestion.
+func (s *settings) suggest(paths []string) (prefix, scope string) {
+	for _, r := range slices.Concat(s.Suggest, defaultSuggestRules) {
+		if !r.matches(paths) {
+			continue
+		}
+		if prefix == "" && r.Prefix != "" && s.prefixIndex(r.Prefix) >= 0 {
+			prefix = r.Prefix
+		}
+		if scope == "" {
+			scope = r.Scope
+		}
+	}
+
+	if scope == "" {
+		dir := commonTopLevelDir(paths)
+		if dir != "" && !strings.HasPrefix(dir, ".") && (len(s.Scopes) == 0 || s.scopeIndex(dir) >= 0) {
+			scope = dir
+		}
+	}
+	return prefix, scope
+}
+
+// scopeIndex returns the position of the named scope, or -1 if it is not configured.
+func (s *settings) scopeIndex(name string) int {
+	for i, scope := range s.Scopes {
+		if scope == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// commonTopLevelDir returns the top-level directory containing all the paths,
+// or an empty string when they do not share one.
+func commonTopLevelDir(paths []string) string {
+	var dir string
+	for _, p := range paths {
+		d, _, ok := strings.Cut(p, "/")
+		if !ok || (dir != "" && d != dir) {
+			return ""
+		}
+		dir = d
+	}
+	return dir
+}
@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestSettingsSuggest(t *testing.T) {
+	tests := []struct {
+		name           string
+		scopes         []string
+		rules          []suggestRule
+		paths          []string
+		expectedPrefix string
+		expectedScope  string
+	}{
+		{
+			name:  "NothingStaged",
+			paths: nil,
+		},
+		{
+			name:           "TestsOnly",
+			paths:          []string{"ui_test.go", "internal/api/api_test.go"},
+			expectedPrefix: "test",
+		},
+		{
+			name:  "TestsAndCode",
+			paths: []string{"ui.go", "ui_test.go"},
+		},
+		{
+			name:           "DocsOnly",
+			paths:          []string{"README.md", "docs/guide/install.txt"},
+			expectedPrefix: "docs",
+		},
+		{
+			name: "CIWithoutPrefix",
+			// "ci" is not a default prefix, and hidden directories are not suggested as scopes.
+			paths: []string{".github/workflows/test.yml"},
+		},
+		{
+			name:           "CommonDirectory",
+			paths:          []string{"api/server.go", "api/server_test.go"},
+			expectedPrefix: "",
+			expectedScope:  "api",
+		},
+		{
+			name:           "CommonDirectoryWithTests",
+			paths:          []string{"api/server_test.go", "api/client_test.go"},
+			expectedPrefix: "test",
+			expectedScope:  "api",
+		},
+		{
+			name:   "CommonDirectoryNotInScopes",
+			scopes: []string{"ui"},
+			paths:  []string{"api/server.go"},
+		},
+		{
+			name:   "ConfiguredRules",
+			scopes: []string{"api", "deps"},
+			rules: []suggestRule{
+				{Paths: []string{"go.mod", "go.sum"}, Prefix: "chore", Scope: "deps"},
+				{Paths: []string{"**/*.go"}, Scope: "api"},
+			},
+			paths:          []string{"go.mod", "go.sum"},
+			expectedPrefix: "chore",
+			expectedScope:  "deps",
+		},
+		{
+			name:           "ConfiguredRulesBeforeDefaults",
+			rules:          []suggestRule{{Paths: []string{"**/*_test.go"}, Prefix: "fix", Scope: "tests"}},
+			paths:          []string{"pkg/a_test.go"},
+			expectedPrefix: "fix",
+			expectedScope:  "tests",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := defaultSettings()
+			s.Scopes = tt.scopes
+			s.Suggest = tt.rules
+
+			prefix, scope := s.suggest(tt.paths)
+			if prefix != tt.expectedPrefix {
+				t.Errorf("expected prefix %q, got %q", tt.expectedPrefix, prefix)
+			}
+			if scope != tt.expectedScope {
+				t.Errorf("expected scope %q, got %q", tt.expectedScope, scope)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// gitTemplateName is the name of the template read from the commit.template file.
+const gitTemplateName = "commit.template"
+
+// commitTemplate is a named template pre-filling the Description, e.g. with "Why" and "How" sections.
+type commitTemplate struct {
+	Name string `toml:"name" yaml:"name"`
+	Body string `toml:"body" yaml:"body"`
+}
+
+// templateVars holds the values of the placeholders expanded in templates.
+type templateVars struct {
+	Branch string
+	Ticket string
+	Author author
+}
+
+// expand replaces the {{branch}}, {{ticket}} and {{author}} placeholders in the text.
+// Other placeholders are left as they are.
+func (v templateVars) expand(text string) string {
+	return strings.NewReplacer(
+		"{{branch}}", v.Branch,
+		"{{ticket}}", v.Ticket,
+		"{{author}}", v.Author.String(),
+	).Replace(text)
+}
+
+// loadTemplates returns the templates with their placeholders expanded: the content of the
+// commit.template file first when it is set, then the configured templates.
+// A relative commit.template path is relative to the repository root, like in git.
+func loadTemplates(repo *git.Repository, root string, cfg *settings, vars templateVars) ([]commitTemplate, error) {
+	var templates []commitTemplate
+
+	p, err := gitConfigValue(repo, "commit", "", "template")
+	if err != nil {
+		return nil, err
+	}
+	if p != "" {
+		if p, err = expandHomeDir(p); err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read commit.template: %w", err)
+		}
+		// Like git, comment lines are template instructions that are not part of the message.
+		templates = append(templates, commitTemplate{Name: gitTemplateName, Body: cleanupMessage(string(data))})
+	}
+	templates = append(templates, cfg.Templates...)
+
+	for i := range templates {
+		templates[i].Body = vars.expand(templates[i].Body)
+	}
+	return templates, nil
+}
+
+// defaultTemplate returns the name of the template applied when the TUI starts:
+// default_template when it is set, else commit.template when it is one of the templates.
+func defaultTemplate(cfg *settings, templates []commitTemplate) string {
+	if cfg.DefaultTemplate != "" {
+		return cfg.DefaultTemplate
+	}
+	for _, t := range templates {
+		if t.Name == gitTemplateName {
+			return t.Name
+		}
+	}
+	return ""
+}
+
+// newTemplateVars returns the placeholder values for the repository, the ticket ID and the commit author.
+func newTemplateVars(repo *git.Repository, ticket string, a author) (templateVars, error) {
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return templateVars{}, err
+	}
+	return templateVars{Branch: branch, Ticket: ticket, Author: a}, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestTemplateVars_Expand(t *testing.T) {
+	v := templateVars{Branch: "feature/PROJ-1", Ticket: "PROJ-1", Author: author{Name: "Tester", Email: "tester@example.com"}}
+	got := v.expand("Branch: {{branch}}\nRefs: {{ticket}}\nBy {{author}} {{unknown}}")
+	expected := "Branch: feature/PROJ-1\nRefs: PROJ-1\nBy Tester <tester@example.com> {{unknown}}"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	isolateGitConfig(t)
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+
+	template := "# Comment lines are dropped, like in git.\nWhy:\n\n{{ticket}}\n"
+	if err := os.WriteFile(filepath.Join(root, ".gitmessage"), []byte(template), 0644); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+	setRepoConfig(t, repo, map[string]string{"commit.template": ".gitmessage"})
+
+	cfg := defaultSettings()
+	cfg.Templates = []commitTemplate{{Name: "feature", Body: "## How\n\nOn {{branch}}"}}
+	vars := templateVars{Branch: "PROJ-7-login", Ticket: "PROJ-7"}
+
+	templates, err := loadTemplates(repo, root, cfg, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []commitTemplate{
+		{Name: gitTemplateName, Body: "Why:\n\nPROJ-7"},
+		{Name: "feature", Body: "## How\n\nOn PROJ-7-login"},
+	}
+	if len(templates) != len(expected) {
+		t.Fatalf("expected %d templates, got %+v", len(expected), templates)
+	}
+	for i := range expected {
+		if templates[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], templates[i])
+		}
+	}
+	if got := defaultTemplate(cfg, templates); got != gitTemplateName {
+		t.Errorf("expected default template %q, got %q", gitTemplateName, got)
+	}
+	// The configured templates are not modified.
+	if cfg.Templates[0].Body != "## How\n\nOn {{branch}}" {
+		t.Errorf("expected the configured template to be kept, got %q", cfg.Templates[0].Body)
+	}
+
+	cfg.DefaultTemplate = "feature"
+	if got := defaultTemplate(cfg, templates); got != "feature" {
+		t.Errorf("expected default template feature, got %q", got)
+	}
+
+	setRepoConfig(t, repo, map[string]string{"commit.template": "missing"})
+	if _, err := loadTemplates(repo, root, cfg, vars); err == nil {
+		t.Error("expected error for a missing commit.template, but got nil")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// defaultTicketPattern matches issue tracker keys such as "PROJ-123" in branch names.
+const defaultTicketPattern = `[A-Z][A-Z0-9]+-[0-9]+`
+
+// Places where the ticket ID of the branch is inserted in the commit message.
+const (
+	ticketInsertNone    = "none"
+	ticketInsertSummary = "summary"
+	ticketInsertScope   = "scope"
+	ticketInsertTrailer = "trailer"
+)
+
+// ticketConfig configures the ticket ID taken from the branch name.
+type ticketConfig struct {
+	// Pattern finds the ticket ID in the branch name; with a capturing group,
+	// the first group is the ticket ID.
+	Pattern string `toml:"pattern" yaml:"pattern"`
+	// Insert is where the ticket ID goes: "summary", "scope", "trailer" or "none".
+	Insert string `toml:"insert" yaml:"insert"`
+	// Trailer is the key of the trailer used with insert = "trailer".
+	Trailer string `toml:"trailer" yaml:"trailer"`
+}
+
+// merge overrides the receiver with every key that is set in o.
+func (c *ticketConfig) merge(o ticketConfig) {
+	if o.Pattern != "" {
+		c.Pattern = o.Pattern
+	}
+	if o.Insert != "" {
+		c.Insert = o.Insert
+	}
+	if o.Trailer != "" {
+		c.Trailer = o.Trailer
+	}
+}
+
+// validate checks the pattern, the insertion place and the trailer key.
+func (c ticketConfig) validate() error {
+	if _, err := regexp.Compile(c.Pattern); err != nil {
+		return fmt.Errorf("invalid ticket pattern: %w", err)
+	}
+	switch c.Insert {
+	case ticketInsertNone, ticketInsertSummary, ticketInsertScope, ticketInsertTrailer:
+	default:
+		return fmt.Errorf("invalid ticket insert %q: must be summary, scope, trailer or none", c.Insert)
+	}
+	if !trailerKeyPattern.MatchString(c.Trailer) {
+		return fmt.Errorf("invalid ticket trailer key %q", c.Trailer)
+	}
+	return nil
+}
+
+// ticketFromBranch returns the ticket ID found in the branch name, or an empty string.
+// When the pattern has a capturing group, the first group is the ticket ID.
+func ticketFromBranch(branch string, pattern *regexp.Regexp) string {
+	match := pattern.FindStringSubmatch(branch)
+	switch {
+	case match == nil:
+		return ""
+	case len(match) > 1:
+		return match[1]
+	}
+	return match[0]
+}
+
+// branchTicket returns the ticket ID in the name of the branch checked out at HEAD.
+func branchTicket(repo *git.Repository, cfg ticketConfig) (string, error) {
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return "", err
+	}
+	// The pattern is checked when the settings are loaded.
+	return ticketFromBranch(branch, regexp.MustCompile(cfg.Pattern)), nil
+}
+
+// applyTicket inserts the ticket ID in the message where the config says: before the summary,
+// as the scope when none is set, or in a trailer. Nothing is inserted when the message already
+// refers to the ticket, including in the Description, e.g. in a "Refs: {{ticket}}" template.
+func applyTicket(m *commitMessage, ticket string, cfg ticketConfig) {
+	if ticket == "" || strings.Contains(m.Summary, ticket) || m.Scope == ticket || strings.Contains(m.Description, ticket) {
+		return
+	}
+	for _, t := range m.Trailers {
+		if strings.Contains(t.Value, ticket) {
+			return
+		}
+	}
+
+	switch cfg.Insert {
+	case ticketInsertSummary:
+		m.Summary = strings.TrimSpace(ticket + " " + m.Summary)
+	case ticketInsertScope:
+		if m.Scope == "" {
+			m.Scope = ticket
+		}
+	case ticketInsertTrailer:
+		m.Trailers = append(m.Trailers, trailer{Key: cfg.Trailer, Value: ticket})
+	}
+}
+
+// withTicket returns a copy of the message with the ticket ID inserted by applyTicket. The
+// message itself is left as typed, so that it can be saved and edited again with another ticket.
+func withTicket(m *commitMessage, ticket string, cfg ticketConfig) *commitMessage {
+	c := *m
+	c.Trailers = slices.Clone(m.Trailers)
+	applyTicket(&c, ticket, cfg)
+	return &c
+}
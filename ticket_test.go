@@ -0,0 +1,150 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestTicketFromBranch(t *testing.T) {
+	tests := []struct {
+		branch   string
+		pattern  string
+		expected string
+	}{
+		{branch: "feature/PROJ-1234-login", pattern: defaultTicketPattern, expected: "PROJ-1234"},
+		{branch: "AB2-7", pattern: defaultTicketPattern, expected: "AB2-7"},
+		{branch: "fix/login", pattern: defaultTicketPattern, expected: ""},
+		{branch: "proj-123", pattern: defaultTicketPattern, expected: ""},
+		{branch: "issue-42-crash", pattern: `issue-([0-9]+)`, expected: "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			if got := ticketFromBranch(tt.branch, regexp.MustCompile(tt.pattern)); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyTicket(t *testing.T) {
+	tests := []struct {
+		name     string
+		insert   string
+		msg      commitMessage
+		ticket   string
+		expected string
+	}{
+		{
+			name:     "Trailer",
+			insert:   ticketInsertTrailer,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login"},
+			ticket:   "PROJ-1",
+			expected: "feat: add login\n\nRefs: PROJ-1",
+		},
+		{
+			name:     "Summary",
+			insert:   ticketInsertSummary,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login"},
+			ticket:   "PROJ-1",
+			expected: "feat: PROJ-1 add login",
+		},
+		{
+			name:     "Scope",
+			insert:   ticketInsertScope,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login"},
+			ticket:   "PROJ-1",
+			expected: "feat(PROJ-1): add login",
+		},
+		{
+			name:     "ScopeAlreadySet",
+			insert:   ticketInsertScope,
+			msg:      commitMessage{Prefix: "feat", Scope: "auth", Summary: "add login"},
+			ticket:   "PROJ-1",
+			expected: "feat(auth): add login",
+		},
+		{
+			name:     "None",
+			insert:   ticketInsertNone,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login"},
+			ticket:   "PROJ-1",
+			expected: "feat: add login",
+		},
+		{
+			name:     "NoTicket",
+			insert:   ticketInsertTrailer,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login"},
+			expected: "feat: add login",
+		},
+		{
+			name:     "ReferencedByTemplate",
+			insert:   ticketInsertTrailer,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login", Description: templateVars{Ticket: "PROJ-1"}.expand("Why:\n\nRefs: {{ticket}}")},
+			ticket:   "PROJ-1",
+			expected: "feat: add login\n\nWhy:\n\nRefs: PROJ-1",
+		},
+		{
+			name:     "AlreadyReferenced",
+			insert:   ticketInsertTrailer,
+			msg:      commitMessage{Prefix: "feat", Summary: "add login", Trailers: []trailer{{Key: "Closes", Value: "PROJ-1"}}},
+			ticket:   "PROJ-1",
+			expected: "feat: add login\n\nCloses: PROJ-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			applyTicket(&msg, tt.ticket, ticketConfig{Pattern: defaultTicketPattern, Insert: tt.insert, Trailer: "Refs"})
+			if got := msg.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTicketConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       ticketConfig
+		expectErr bool
+	}{
+		{name: "Default", cfg: defaultSettings().Ticket},
+		{name: "InvalidPattern", cfg: ticketConfig{Pattern: "[", Insert: ticketInsertNone, Trailer: "Refs"}, expectErr: true},
+		{name: "InvalidInsert", cfg: ticketConfig{Pattern: defaultTicketPattern, Insert: "body", Trailer: "Refs"}, expectErr: true},
+		{name: "InvalidTrailer", cfg: ticketConfig{Pattern: defaultTicketPattern, Insert: ticketInsertTrailer, Trailer: "Re fs"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate()
+			if tt.expectErr && err == nil {
+				t.Error("expected error, but got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestBranchTicket(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/feature/PROJ-1234-login")); err != nil {
+		t.Fatalf("failed to set HEAD: %v", err)
+	}
+
+	ticket, err := branchTicket(repo, defaultSettings().Ticket)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ticket != "PROJ-1234" {
+		t.Errorf("expected PROJ-1234, got %q", ticket)
+	}
+}
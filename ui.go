@@ -1,52 +1,215 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Focus indexes of the TUI elements, in Tab order.
+const (
+	focusPrefix = iota
+	focusScope
+	focusSummary
+	focusDescription
+	focusBreaking
+	focusBreakingChange
+	focusTrailers
+	focusTicket
+	focusAuthor
+	focusFiles
+	focusCommit
+	focusQuit
+	focusCount
+)
+
+// Styles shared by the views.
+var (
+	focusLabelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#f7b977")).Bold(true)
+	noFocusLabelStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#585858"))
+	// Style for displaying input values set to white (here "#e6eae6").
+	inputStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#e6eae6"))
+	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#e06c75"))
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#e5c07b"))
+	stagedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#98c379"))
+	hunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#56b6c2"))
+	fileStyle    = lipgloss.NewStyle().Bold(true)
+)
+
+// maxFileRows is the number of files shown at once in the staging panel.
+const maxFileRows = 10
+
+// maxDiffRows is the number of diff lines shown at once in the hunk view.
+const maxDiffRows = 16
+
+// maxHistoryRows is the number of messages shown at once in the history picker.
+const maxHistoryRows = 10
+
+// maxPreviewRows is the height of the staged diff preview.
+const maxPreviewRows = 20
+
+// hunkView is the diff of a file being staged by hunk or by line in the staging panel.
+type hunkView struct {
+	path     string
+	diff     *fileDiff
+	cursor   int          // index of the changed line under the cursor in diff.Lines
+	selected map[int]bool // changed lines to stage, by index in diff.Lines
+}
+
+// tuiOptions holds the repository data used by the TUI besides the git-cm settings.
+type tuiOptions struct {
+	Author    author         // the commit author, which can be changed in the TUI
+	Committer author         // the committer, used for Signed-off-by trailers
+	Authors   []string       // past commit authors ("Name <email>"), offered for Co-authored-by and the author
+	Message   *commitMessage // pre-populates the input fields when not nil
+	Err       error          // the error of a failed commit attempt, shown above the input fields
+	Amend     bool           // the commit replaces HEAD
+	Staging   *stagingArea   // shows the staging panel when not nil
+	History   []historyEntry // saved messages offered by the history picker, the most recent first
+	Drafts    *draftStore    // autosaves the message when not nil
+	Draft     *draft         // a draft of a previous session, offered for restoring
+	Templates []commitTemplate
+	Template  string // the template applied when the TUI starts without a message
+	Ticket    string // the ticket ID of the branch
+}
+
+// retry sets the options of the TUI reopened after a failed commit: the message, the ticket ID
+// and the author are kept with the error, so that the message can be fixed and committed again.
+// The draft of a previous session is not offered again, since the user has moved on from it.
+func (o *tuiOptions) retry(msg *commitMessage, ticket string, a author, err error) {
+	o.Message = msg
+	o.Ticket = ticket
+	o.Author = a
+	o.Err = err
+	o.Draft = nil
+}
+
+// maxHookOutputLines is the number of trailing lines of hook output shown with a commit error.
+const maxHookOutputLines = 20
+
 // commitModel is the model that holds the state of the TUI.
-// (Note) Focus indexes:
+// (Note) Focus indexes are defined by the focus* constants:
 //
-//	0: Prefix, 1: Summary, 2: Description, 3: Commit, 4: Quit
+//	Prefix, Scope, Summary, Description, Breaking, BREAKING CHANGE, Trailers, Ticket, Author, Files, Commit, Quit
 //
-// Also, summaryEditing and descEditing are used to manage the input mode triggered by the "i" or "enter" key.
+// The BREAKING CHANGE footer can only be focused while the Breaking toggle is on, the Ticket
+// field only when the ticket ID is inserted in the message, and the Files panel only when the
+// TUI has a staging area.
+// Also, scopeEditing, summaryEditing, descEditing, breakingEditing, trailerEditing, ticketEditing
+// and authorEditing are used to manage the input mode triggered by the "i" or "enter" key.
 type commitModel struct {
-	prefixOptions      []string
+	cfg *settings
+
+	prefixOptions      []prefixOption
 	currentPrefixIndex int
 	dropdownIndex      int
 	prefixDropdownOpen bool
 
+	scope              textinput.Model
+	scopeOptions       []string
+	scopeDropdownIndex int
+	scopeDropdownOpen  bool
+	scopeEditing       bool
+
 	summary        textinput.Model
 	desc           textarea.Model
-	focusIndex     int // 0: Prefix, 1: Summary, 2: Description, 3: Commit, 4: Quit
+	focusIndex     int
 	summaryEditing bool
 	descEditing    bool
 
+	breaking        bool
+	breakingDesc    textarea.Model
+	breakingEditing bool
+
+	committer      author
+	trailers       []trailer
+	trailerIndex   int // the selected trailer, removed with "d" and edited with "e"
+	trailerInput   textinput.Model
+	trailerEditing bool
+	trailerReplace bool // the input edits the selected trailer instead of adding one
+	trailerErr     string
+
+	ticketInput   textinput.Model
+	ticketEditing bool
+
+	author        author
+	authorInput   textinput.Model
+	authorEditing bool
+	authorErr     string
+
+	staging   *stagingArea
+	files     []fileStatus
+	fileIndex int
+	filesErr  string
+	hunks     *hunkView
+
+	history      []historyEntry
+	historyOpen  bool
+	historyIndex int
+
+	templates     []commitTemplate
+	template      string // the name of the applied template
+	templateOpen  bool
+	templateIndex int
+
+	drafts     *draftStore
+	draftOffer *draft // the draft to restore, until the user answers
+	savedDraft string // the last saved draft, to skip saving an unchanged message
+	draftErr   string
+
+	// suggest preselects the prefix and the scope inferred from the staged paths, as long as
+	// they still hold the previous suggestions (autoPrefix and autoScope).
+	suggest    bool
+	autoPrefix string
+	autoScope  string
+
+	previewOpen   bool
+	preview       []string // rendered lines of the staged diff
+	previewStats  diffStats
+	previewOffset int
+	previewErr    string
+
+	commitErr    string
+	commitOutput []string
+	amend        bool
+
 	commitSelected bool
 	quitSelected   bool
 }
 
-// newCommitModel initializes and returns a new commitModel.
-func newCommitModel() *commitModel {
+// newCommitModel initializes and returns a new commitModel using the prefixes, scopes,
+// default prefix and summary limit from the given settings.
+func newCommitModel(cfg *settings) *commitModel {
 	m := &commitModel{
-		prefixOptions:      []string{"feat", "fix", "refactor", "test", "style", "chore", "docs"},
-		currentPrefixIndex: 0,
-		focusIndex:         0,
+		cfg:                cfg,
+		prefixOptions:      cfg.Prefixes,
+		currentPrefixIndex: max(cfg.prefixIndex(cfg.DefaultPrefix), 0),
+		scopeOptions:       cfg.Scopes,
+		focusIndex:         focusPrefix,
 		prefixDropdownOpen: false,
 		dropdownIndex:      0,
 		summaryEditing:     false,
 		descEditing:        false,
 	}
 
+	// Initialize the text input field for Scope.
+	si := textinput.New()
+	si.Prompt = ""
+	si.Width = 50
+	m.scope = si
+
 	// Initialize the text input field for Summary.
 	ti := textinput.New()
 	ti.Prompt = ""
-	ti.CharLimit = 100
+	ti.CharLimit = cfg.SummaryLimit
 	ti.Width = 50
 	m.summary = ti
 
@@ -57,14 +220,574 @@ func newCommitModel() *commitModel {
 	ta.ShowLineNumbers = false
 	m.desc = ta
 
+	// Initialize the text area for the BREAKING CHANGE footer.
+	ba := textarea.New()
+	ba.SetWidth(50)
+	ba.SetHeight(2)
+	ba.ShowLineNumbers = false
+	m.breakingDesc = ba
+
+	// Initialize the text input field for new trailers. Suggestions are accepted with the
+	// right arrow key because Tab moves the focus.
+	tr := textinput.New()
+	tr.Prompt = "+ "
+	tr.Placeholder = "Key: Value"
+	tr.Width = 50
+	tr.ShowSuggestions = true
+	tr.KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
+	m.trailerInput = tr
+
+	// Initialize the text input field for the ticket ID.
+	ki := textinput.New()
+	ki.Prompt = ""
+	ki.Placeholder = "none"
+	ki.Width = 50
+	m.ticketInput = ki
+
+	// Initialize the text input field for the commit author.
+	ai := textinput.New()
+	ai.Prompt = ""
+	ai.Placeholder = "Name <email>"
+	ai.Width = 50
+	ai.ShowSuggestions = true
+	ai.KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
+	m.authorInput = ai
+
 	return m
 }
 
-// Init is the command that is executed initially (unused in this case).
+// draftTickMsg triggers the periodic draft autosave.
+type draftTickMsg struct{}
+
+// draftTick schedules the next draft autosave.
+func draftTick() tea.Cmd {
+	return tea.Tick(draftSaveInterval, func(time.Time) tea.Msg {
+		return draftTickMsg{}
+	})
+}
+
+// Init is the command that is executed initially: it starts the draft autosave.
 func (m *commitModel) Init() tea.Cmd {
+	if m.drafts != nil {
+		return draftTick()
+	}
 	return nil
 }
 
+// editing reports whether any input field is in input mode.
+func (m *commitModel) editing() bool {
+	return m.scopeEditing || m.summaryEditing || m.descEditing || m.breakingEditing || m.trailerEditing ||
+		m.ticketEditing || m.authorEditing || m.hunks != nil || m.historyOpen || m.templateOpen || m.draftOffer != nil
+}
+
+// setAuthors sets the committer used for Signed-off-by trailers, and offers the
+// well-known trailers as suggestions in the trailer input, completing Co-authored-by
+// and Reviewed-by with the given past commit authors. The past authors are also
+// suggested in the author input.
+func (m *commitModel) setAuthors(self author, authors []string) {
+	m.committer = self
+	m.authorInput.SetSuggestions(authors)
+
+	suggestions := []string{"Signed-off-by: " + self.String()}
+	for _, a := range authors {
+		if a != self.String() {
+			suggestions = append(suggestions, "Co-authored-by: "+a)
+		}
+	}
+	for _, a := range authors {
+		suggestions = append(suggestions, "Reviewed-by: "+a)
+	}
+	suggestions = append(suggestions, "Refs: ")
+	m.trailerInput.SetSuggestions(suggestions)
+}
+
+// setCommitAuthor sets the author of the commit shown in the author field.
+func (m *commitModel) setCommitAuthor(a author) {
+	m.author = a
+	m.authorInput.SetValue(a.String())
+}
+
+// setMessage pre-populates the input fields with the values of msg.
+// A prefix that is not configured leaves the current prefix selected.
+func (m *commitModel) setMessage(msg *commitMessage) {
+	for i, option := range m.prefixOptions {
+		if option.Name == msg.Prefix {
+			m.currentPrefixIndex = i
+		}
+	}
+	m.scope.SetValue(msg.Scope)
+	m.summary.SetValue(msg.Summary)
+	m.desc.SetValue(msg.Description)
+	m.template = ""
+	m.breaking = msg.Breaking
+	m.breakingDesc.SetValue(msg.BreakingChange)
+	m.trailers = append([]trailer(nil), msg.Trailers...)
+	m.trailerIndex = 0
+}
+
+// addTrailer parses and validates the "Key: Value" text and appends it to the trailers,
+// selecting it. A Signed-off-by trailer without a value is filled with the committer.
+func (m *commitModel) addTrailer(text string) error {
+	return m.setTrailer(len(m.trailers), text)
+}
+
+// setTrailer parses and validates the "Key: Value" text and replaces the trailer at index i with
+// it, or appends it when i is len(m.trailers). The trailer is selected.
+func (m *commitModel) setTrailer(i int, text string) error {
+	if key := strings.TrimSuffix(strings.TrimSpace(text), ":"); strings.EqualFold(strings.TrimSpace(key), "Signed-off-by") {
+		text = "Signed-off-by: " + m.committer.String()
+	}
+
+	t, err := parseTrailer(text)
+	if err != nil {
+		return err
+	}
+
+	// The replaced trailer is left out of the duplicate check.
+	others := slices.Clone(m.trailers)
+	if i < len(others) {
+		others = slices.Delete(others, i, i+1)
+	}
+	msg := commitMessage{Trailers: others}
+	if err := msg.addTrailer(t); err != nil {
+		return err
+	}
+	m.trailers = slices.Insert(others, i, t)
+	m.trailerIndex = i
+	return nil
+}
+
+// removeTrailer removes the selected trailer and selects the next one, or the new last one.
+func (m *commitModel) removeTrailer() {
+	if m.trailerIndex >= len(m.trailers) {
+		return
+	}
+	m.trailers = slices.Delete(m.trailers, m.trailerIndex, m.trailerIndex+1)
+	m.trailerIndex = max(min(m.trailerIndex, len(m.trailers)-1), 0)
+}
+
+// stopTrailerEditing leaves the input mode of the trailers. The text of an edited trailer is
+// dropped, leaving the trailer unchanged unless it was saved.
+func (m *commitModel) stopTrailerEditing() {
+	if m.trailerReplace {
+		m.trailerInput.Reset()
+	}
+	m.trailerEditing = false
+	m.trailerReplace = false
+	m.trailerInput.Blur()
+}
+
+// focusable reports whether the element at the given focus index can receive the focus.
+func (m *commitModel) focusable(index int) bool {
+	switch index {
+	case focusBreakingChange:
+		return m.breaking
+	case focusTicket:
+		return m.cfg.Ticket.Insert != ticketInsertNone
+	case focusFiles:
+		return m.staging != nil
+	}
+	return true
+}
+
+// setStaging shows the staging panel for the given staging area.
+func (m *commitModel) setStaging(s *stagingArea) {
+	m.staging = s
+	m.refreshFiles()
+}
+
+// refreshFiles reloads the changed files of the staging area, keeping the cursor in range.
+func (m *commitModel) refreshFiles() {
+	files, err := m.staging.files()
+	if err != nil {
+		m.filesErr = err.Error()
+		return
+	}
+	m.files = files
+	m.fileIndex = min(m.fileIndex, max(len(files)-1, 0))
+	if m.suggest {
+		m.applySuggestion()
+	}
+	if m.previewOpen {
+		m.refreshPreview()
+	}
+}
+
+// enableSuggestions starts preselecting the prefix and the scope inferred from the staged paths,
+// replacing the default prefix and an empty scope.
+func (m *commitModel) enableSuggestions() {
+	m.suggest = true
+	m.autoPrefix = m.prefixOptions[m.currentPrefixIndex].Name
+	m.autoScope = m.scope.Value()
+	if m.staging != nil {
+		m.applySuggestion()
+	}
+}
+
+// applySuggestion preselects the prefix and the scope inferred from the staged paths.
+// Values changed by the user are kept.
+func (m *commitModel) applySuggestion() {
+	var paths []string
+	for _, f := range m.files {
+		if f.staged() {
+			paths = append(paths, f.Path)
+		}
+	}
+	prefix, scope := m.cfg.suggest(paths)
+	if prefix == "" {
+		prefix = m.cfg.DefaultPrefix
+	}
+
+	if m.prefixOptions[m.currentPrefixIndex].Name == m.autoPrefix {
+		if i := m.cfg.prefixIndex(prefix); i >= 0 {
+			m.currentPrefixIndex = i
+			m.autoPrefix = prefix
+		}
+	}
+	if m.scope.Value() == m.autoScope {
+		m.scope.SetValue(scope)
+		m.autoScope = scope
+	}
+}
+
+// autosaveDraft saves the message as the draft of the branch when it changed since the last save.
+// Nothing is saved while the restore of a previous draft is offered, so that it is not overwritten.
+func (m *commitModel) autosaveDraft() {
+	if m.drafts == nil || m.draftOffer != nil {
+		return
+	}
+
+	msg := m.fields()
+	if m.blank() || msg.String() == m.savedDraft {
+		return
+	}
+	m.draftErr = ""
+	if err := m.drafts.save(msg); err != nil {
+		m.draftErr = err.Error()
+		return
+	}
+	m.savedDraft = msg.String()
+}
+
+// answerDraftOffer restores the offered draft, or discards it.
+func (m *commitModel) answerDraftOffer(restore bool) {
+	if restore {
+		m.setMessage(m.draftOffer.Message)
+		m.suggest = false
+	}
+	m.draftOffer = nil
+}
+
+// toggleTemplates opens or closes the template switcher, with the applied template selected.
+// The first option is "no template".
+func (m *commitModel) toggleTemplates() {
+	m.templateOpen = !m.templateOpen
+	m.templateIndex = 0
+	for i, t := range m.templates {
+		if t.Name == m.template {
+			m.templateIndex = i + 1
+		}
+	}
+}
+
+// applyTemplate replaces the Description with the body of the named template,
+// or clears it when name is empty. It reports whether the template exists.
+func (m *commitModel) applyTemplate(name string) bool {
+	if name == "" {
+		m.desc.SetValue("")
+		m.template = ""
+		return true
+	}
+	for _, t := range m.templates {
+		if t.Name == name {
+			m.desc.SetValue(t.Body)
+			m.template = name
+			return true
+		}
+	}
+	return false
+}
+
+// toggleHistory opens or closes the history picker.
+func (m *commitModel) toggleHistory() {
+	m.historyOpen = !m.historyOpen
+	m.historyIndex = 0
+}
+
+// recallMessage replaces the message with the one selected in the history picker.
+func (m *commitModel) recallMessage() {
+	if m.historyIndex < len(m.history) {
+		m.setMessage(m.history[m.historyIndex].Message)
+		m.suggest = false
+	}
+	m.historyOpen = false
+}
+
+// togglePreview shows or hides the staged diff preview.
+func (m *commitModel) togglePreview() {
+	if m.staging == nil {
+		return
+	}
+	m.previewOpen = !m.previewOpen
+	m.previewOffset = 0
+	if m.previewOpen {
+		m.refreshPreview()
+	}
+}
+
+// refreshPreview renders the staged diff into the preview lines, keeping the scroll offset in range.
+func (m *commitModel) refreshPreview() {
+	diffs, err := m.staging.stagedDiff()
+	m.previewErr = ""
+	if err != nil {
+		m.previewErr = err.Error()
+		return
+	}
+
+	m.preview = nil
+	m.previewStats = diffStats{}
+	for _, fd := range diffs {
+		m.previewStats.add(fd.Diff)
+		m.preview = append(m.preview, fileStyle.Render("diff --git a/"+fd.Path+" b/"+fd.Path))
+		if fd.Diff == nil {
+			m.preview = append(m.preview, noFocusLabelStyle.Render("Binary file differs"))
+			continue
+		}
+		for _, h := range fd.Diff.Hunks {
+			m.preview = append(m.preview, hunkStyle.Render(h.header()))
+			for _, l := range fd.Diff.Lines[h.Start:h.End] {
+				line := string(l.Op) + strings.TrimRight(l.Text, "\r\n")
+				switch l.Op {
+				case '+':
+					line = stagedStyle.Render(line)
+				case '-':
+					line = errorStyle.Render(line)
+				}
+				m.preview = append(m.preview, line)
+			}
+		}
+	}
+	m.scrollPreview(0)
+}
+
+// scrollPreview scrolls the staged diff preview by the given number of lines.
+func (m *commitModel) scrollPreview(lines int) {
+	m.previewOffset = max(min(m.previewOffset+lines, len(m.preview)-maxPreviewRows), 0)
+}
+
+// hasStagedFiles reports whether any file of the staging panel has staged changes.
+func (m *commitModel) hasStagedFiles() bool {
+	for _, f := range m.files {
+		if f.staged() {
+			return true
+		}
+	}
+	return false
+}
+
+// violations returns the problems of the message shown in the TUI: the lint violations, and the
+// summary going over summary_limit, e.g. with the ticket ID inserted, reported as an error.
+func (m *commitModel) violations() []lintViolation {
+	msg := m.message()
+	violations := lintMessage(msg, m.cfg)
+	if err := msg.checkSummaryLimit(m.cfg); err != nil {
+		violations = append(violations, lintViolation{Rule: "summary_limit", Severity: severityError, Message: err.Error()})
+	}
+	return violations
+}
+
+// canCommit reports whether the Commit button can be pressed: the message has no lint errors,
+// its summary is within the limit, and something is staged unless amending. Without a staging
+// panel, staged files are checked when committing, or by git itself with --message-file.
+func (m *commitModel) canCommit() bool {
+	if hasLintErrors(m.violations()) {
+		return false
+	}
+	return m.staging == nil || m.amend || m.hasStagedFiles()
+}
+
+// toggleFile stages the file under the cursor, or unstages it when it has no unstaged changes.
+func (m *commitModel) toggleFile() {
+	if len(m.files) == 0 {
+		return
+	}
+
+	f := m.files[m.fileIndex]
+	var err error
+	if f.unstaged() {
+		err = m.staging.stage(f.Path)
+	} else {
+		err = m.staging.unstage(f.Path)
+	}
+	m.filesErr = ""
+	if err != nil {
+		m.filesErr = err.Error()
+	}
+	m.refreshFiles()
+}
+
+// stageAllFiles stages every unstaged change, like `git add --all`.
+func (m *commitModel) stageAllFiles() {
+	m.filesErr = ""
+	for _, f := range m.files {
+		if !f.unstaged() {
+			continue
+		}
+		if err := m.staging.stage(f.Path); err != nil {
+			m.filesErr = err.Error()
+			break
+		}
+	}
+	m.refreshFiles()
+}
+
+// openHunkView shows the unstaged changes of the file under the cursor for staging by hunk.
+func (m *commitModel) openHunkView() {
+	if len(m.files) == 0 {
+		return
+	}
+
+	path := m.files[m.fileIndex].Path
+	d, err := m.staging.diff(path)
+	m.filesErr = ""
+	if err != nil {
+		m.filesErr = err.Error()
+		return
+	}
+	if len(d.Hunks) == 0 {
+		m.filesErr = path + " has no unstaged changes"
+		return
+	}
+	m.hunks = &hunkView{path: path, diff: d, cursor: -1, selected: map[int]bool{}}
+	m.moveHunkCursor(1)
+}
+
+// moveHunkCursor moves the cursor of the hunk view to the next (step 1) or previous (step -1)
+// changed line, if any.
+func (m *commitModel) moveHunkCursor(step int) {
+	lines := m.hunks.diff.Lines
+	for i := m.hunks.cursor + step; i >= 0 && i < len(lines); i += step {
+		if lines[i].changed() {
+			m.hunks.cursor = i
+			return
+		}
+	}
+}
+
+// moveHunk moves the cursor of the hunk view to the first changed line of the next (step 1)
+// or previous (step -1) hunk, if any.
+func (m *commitModel) moveHunk(step int) {
+	d := m.hunks.diff
+	i := d.hunkAt(m.hunks.cursor) + step
+	if i < 0 || i >= len(d.Hunks) {
+		return
+	}
+	m.hunks.cursor = d.Hunks[i].Start - 1
+	m.moveHunkCursor(1)
+}
+
+// toggleHunk selects every changed line of the hunk under the cursor, or deselects them
+// when they are all selected already.
+func (m *commitModel) toggleHunk() {
+	h := m.hunks.diff.Hunks[m.hunks.diff.hunkAt(m.hunks.cursor)]
+	lines := m.hunks.diff.Lines
+
+	all := true
+	for i := h.Start; i < h.End; i++ {
+		if lines[i].changed() && !m.hunks.selected[i] {
+			all = false
+		}
+	}
+	for i := h.Start; i < h.End; i++ {
+		if lines[i].changed() {
+			m.hunks.selected[i] = !all
+		}
+	}
+}
+
+// stageSelectedLines stages the selected lines of the hunk view, and then shows the remaining
+// unstaged changes of the file, or closes the view when there are none.
+func (m *commitModel) stageSelectedLines() {
+	v := m.hunks
+	m.filesErr = ""
+	if err := m.staging.stageLines(v.path, v.diff, v.selected); err != nil {
+		m.filesErr = err.Error()
+		return
+	}
+	m.refreshFiles()
+
+	m.hunks = nil
+	for i, f := range m.files {
+		if f.Path == v.path && f.unstaged() {
+			m.fileIndex = i
+			m.openHunkView()
+			break
+		}
+	}
+}
+
+// moveFocus moves the focus by step (1 or -1), wrapping around and skipping
+// elements that cannot currently be focused.
+func (m *commitModel) moveFocus(step int) {
+	m.leaveFocus()
+	for {
+		m.focusIndex = (m.focusIndex + step + focusCount) % focusCount
+		if m.focusable(m.focusIndex) {
+			return
+		}
+	}
+}
+
+// leaveFocus exits the input mode and closes the dropdown of the focused element
+// before the focus moves elsewhere.
+func (m *commitModel) leaveFocus() {
+	switch m.focusIndex {
+	case focusPrefix:
+		m.prefixDropdownOpen = false
+	case focusScope:
+		m.scopeDropdownOpen = false
+		if m.scopeEditing {
+			m.scopeEditing = false
+			m.scope.Blur()
+		}
+	case focusSummary:
+		if m.summaryEditing {
+			m.summaryEditing = false
+			m.summary.Blur()
+		}
+	case focusDescription:
+		if m.descEditing {
+			m.descEditing = false
+			m.desc.Blur()
+		}
+	case focusBreakingChange:
+		if m.breakingEditing {
+			m.breakingEditing = false
+			m.breakingDesc.Blur()
+		}
+	case focusTrailers:
+		m.trailerErr = ""
+		if m.trailerEditing {
+			m.stopTrailerEditing()
+		}
+	case focusFiles:
+		m.hunks = nil
+	case focusTicket:
+		if m.ticketEditing {
+			m.ticketEditing = false
+			m.ticketInput.Blur()
+		}
+	case focusAuthor:
+		// Leaving the field discards an author that was not confirmed with enter.
+		m.authorErr = ""
+		if m.authorEditing {
+			m.authorEditing = false
+			m.authorInput.Blur()
+			m.authorInput.SetValue(m.author.String())
+		}
+	}
+}
+
 // Update processes the user input events.
 func (m *commitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -76,47 +799,101 @@ func (m *commitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Quit when "q" is pressed and not in input mode.
-		if !m.summaryEditing && !m.descEditing && msg.String() == "q" {
+		if !m.editing() && msg.String() == "q" {
 			m.quitSelected = true
 			return m, tea.Quit
 		}
 
-		// Global focus movement: Tab / Shift+Tab.
-		switch msg.String() {
-		case "tab":
-			// Exit input mode for Summary and Description.
-			if m.focusIndex == 1 && m.summaryEditing {
-				m.summaryEditing = false
-				m.summary.Blur()
+		// The draft restore prompt waits for an answer.
+		if m.draftOffer != nil {
+			switch msg.String() {
+			case "y", "enter":
+				m.answerDraftOffer(true)
+			case "n", "esc":
+				m.answerDraftOffer(false)
 			}
-			if m.focusIndex == 2 && m.descEditing {
-				m.descEditing = false
-				m.desc.Blur()
+			return m, nil
+		}
+
+		// The template switcher takes all the keys while it is open.
+		if m.templateOpen {
+			switch msg.String() {
+			case "up", "k":
+				if m.templateIndex > 0 {
+					m.templateIndex--
+				}
+			case "down", "j":
+				if m.templateIndex < len(m.templates) {
+					m.templateIndex++
+				}
+			case "enter":
+				name := ""
+				if m.templateIndex > 0 {
+					name = m.templates[m.templateIndex-1].Name
+				}
+				m.applyTemplate(name)
+				m.templateOpen = false
+			case "esc", "ctrl+g":
+				m.templateOpen = false
 			}
-			if m.focusIndex == 0 && m.prefixDropdownOpen {
-				m.prefixDropdownOpen = false
+			return m, nil
+		}
+
+		// The history picker takes all the keys while it is open.
+		if m.historyOpen {
+			switch msg.String() {
+			case "up", "k":
+				if m.historyIndex > 0 {
+					m.historyIndex--
+				}
+			case "down", "j":
+				if m.historyIndex < len(m.history)-1 {
+					m.historyIndex++
+				}
+			case "enter":
+				m.recallMessage()
+			case "esc", "ctrl+r":
+				m.historyOpen = false
 			}
-			m.focusIndex = (m.focusIndex + 1) % 5
+			return m, nil
+		}
+
+		// Global focus movement: Tab / Shift+Tab.
+		switch msg.String() {
+		case "tab":
+			m.moveFocus(1)
 			return m, nil
 
 		case "shift+tab":
-			if m.focusIndex == 1 && m.summaryEditing {
-				m.summaryEditing = false
-				m.summary.Blur()
-			}
-			if m.focusIndex == 2 && m.descEditing {
-				m.descEditing = false
-				m.desc.Blur()
-			}
-			if m.focusIndex == 0 && m.prefixDropdownOpen {
-				m.prefixDropdownOpen = false
-			}
-			m.focusIndex = (m.focusIndex - 1 + 5) % 5
+			m.moveFocus(-1)
+			return m, nil
+
+		// The pickers and the staged diff preview are opened from any element.
+		case "ctrl+g":
+			m.toggleTemplates()
+			return m, nil
+
+		case "ctrl+r":
+			m.toggleHistory()
+			return m, nil
+
+		case "ctrl+o":
+			m.togglePreview()
 			return m, nil
+
+		case "pgup", "pgdown":
+			if m.previewOpen {
+				if msg.String() == "pgup" {
+					m.scrollPreview(-maxPreviewRows / 2)
+				} else {
+					m.scrollPreview(maxPreviewRows / 2)
+				}
+				return m, nil
+			}
 		}
 
 		switch m.focusIndex {
-		case 0: // Operations for the Prefix.
+		case focusPrefix: // Operations for the Prefix.
 			if m.prefixDropdownOpen {
 				switch msg.String() {
 				case "up", "k":
@@ -141,7 +918,54 @@ func (m *commitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 			}
-		case 1: // For the Summary input field.
+		case focusScope: // For the Scope input field and its picker.
+			if m.scopeDropdownOpen {
+				switch msg.String() {
+				case "up", "k":
+					if m.scopeDropdownIndex > 0 {
+						m.scopeDropdownIndex--
+					}
+				case "down", "j":
+					if m.scopeDropdownIndex < len(m.scopeOptions)-1 {
+						m.scopeDropdownIndex++
+					}
+				case "enter":
+					m.scope.SetValue(m.scopeOptions[m.scopeDropdownIndex])
+					m.scopeDropdownOpen = false
+				case "esc":
+					m.scopeDropdownOpen = false
+				}
+				return m, nil
+			}
+			if !m.scopeEditing {
+				// "enter" opens the picker when scopes are configured; "i" always edits the free text.
+				if msg.String() == "enter" && len(m.scopeOptions) > 0 {
+					m.scopeDropdownOpen = true
+					m.scopeDropdownIndex = 0
+					for i, option := range m.scopeOptions {
+						if option == m.scope.Value() {
+							m.scopeDropdownIndex = i
+						}
+					}
+					return m, nil
+				}
+				if msg.String() == "i" || msg.String() == "enter" {
+					m.scopeEditing = true
+					m.scope.Focus()
+					return m, nil
+				}
+			}
+			if (msg.String() == "esc" || msg.String() == "enter") && m.scopeEditing {
+				m.scopeEditing = false
+				m.scope.Blur()
+				return m, nil
+			}
+			if m.scopeEditing {
+				var cmd tea.Cmd
+				m.scope, cmd = m.scope.Update(msg)
+				return m, cmd
+			}
+		case focusSummary: // For the Summary input field.
 			// Start input mode when "i" or "enter" is pressed and not already editing.
 			if (msg.String() == "i" || msg.String() == "enter") && !m.summaryEditing {
 				m.summaryEditing = true
@@ -159,7 +983,7 @@ func (m *commitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.summary, cmd = m.summary.Update(msg)
 				return m, cmd
 			}
-		case 2: // For the Description input area.
+		case focusDescription: // For the Description input area.
 			if (msg.String() == "i" || msg.String() == "enter") && !m.descEditing {
 				m.descEditing = true
 				m.desc.Focus()
@@ -175,112 +999,645 @@ func (m *commitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.desc, cmd = m.desc.Update(msg)
 				return m, cmd
 			}
-		case 3: // Commit button selected.
-			if msg.String() == "enter" {
+		case focusBreaking: // Breaking change toggle.
+			if msg.String() == "enter" || msg.String() == " " {
+				m.breaking = !m.breaking
+				return m, nil
+			}
+		case focusBreakingChange: // For the BREAKING CHANGE footer input area.
+			if (msg.String() == "i" || msg.String() == "enter") && !m.breakingEditing {
+				m.breakingEditing = true
+				m.breakingDesc.Focus()
+				return m, nil
+			}
+			if msg.String() == "esc" && m.breakingEditing {
+				m.breakingEditing = false
+				m.breakingDesc.Blur()
+				return m, nil
+			}
+			if m.breakingEditing {
+				var cmd tea.Cmd
+				m.breakingDesc, cmd = m.breakingDesc.Update(msg)
+				return m, cmd
+			}
+		case focusTrailers: // For the trailer list and its input field.
+			if m.trailerEditing {
+				switch msg.String() {
+				case "esc":
+					m.stopTrailerEditing()
+					m.trailerErr = ""
+					return m, nil
+				case "enter":
+					if m.trailerReplace {
+						if err := m.setTrailer(m.trailerIndex, m.trailerInput.Value()); err != nil {
+							m.trailerErr = err.Error()
+							return m, nil
+						}
+						m.trailerErr = ""
+						m.stopTrailerEditing()
+						return m, nil
+					}
+					// Add the trailer and keep editing so that several trailers can be entered in a row.
+					if err := m.addTrailer(m.trailerInput.Value()); err != nil {
+						m.trailerErr = err.Error()
+						return m, nil
+					}
+					m.trailerErr = ""
+					m.trailerInput.Reset()
+					return m, nil
+				}
+				var cmd tea.Cmd
+				m.trailerInput, cmd = m.trailerInput.Update(msg)
+				return m, cmd
+			}
+			switch msg.String() {
+			case "up", "k":
+				if m.trailerIndex > 0 {
+					m.trailerIndex--
+				}
+				return m, nil
+			case "down", "j":
+				if m.trailerIndex < len(m.trailers)-1 {
+					m.trailerIndex++
+				}
+				return m, nil
+			case "i", "enter":
+				m.trailerEditing = true
+				return m, m.trailerInput.Focus()
+			case "e": // Edit the selected trailer in the input field.
+				if m.trailerIndex < len(m.trailers) {
+					m.trailerEditing = true
+					m.trailerReplace = true
+					m.trailerInput.SetValue(m.trailers[m.trailerIndex].String())
+					m.trailerInput.CursorEnd()
+					return m, m.trailerInput.Focus()
+				}
+				return m, nil
+			case "s": // Sign off, like `git commit -s`.
+				if err := m.addTrailer("Signed-off-by"); err != nil {
+					m.trailerErr = err.Error()
+				}
+				return m, nil
+			case "d", "backspace": // Remove the selected trailer.
+				m.removeTrailer()
+				return m, nil
+			}
+		case focusTicket: // For the ticket ID input field.
+			if (msg.String() == "i" || msg.String() == "enter") && !m.ticketEditing {
+				m.ticketEditing = true
+				m.ticketInput.Focus()
+				return m, nil
+			}
+			if (msg.String() == "esc" || msg.String() == "enter") && m.ticketEditing {
+				m.ticketEditing = false
+				m.ticketInput.Blur()
+				return m, nil
+			}
+			if m.ticketEditing {
+				var cmd tea.Cmd
+				m.ticketInput, cmd = m.ticketInput.Update(msg)
+				return m, cmd
+			}
+		case focusAuthor: // For the commit author input field.
+			if !m.authorEditing {
+				if msg.String() == "i" || msg.String() == "enter" {
+					m.authorEditing = true
+					return m, m.authorInput.Focus()
+				}
+				return m, nil
+			}
+			switch msg.String() {
+			case "esc":
+				m.leaveFocus()
+				return m, nil
+			case "enter":
+				a, err := parseIdent(m.authorInput.Value())
+				if err != nil {
+					m.authorErr = err.Error()
+					return m, nil
+				}
+				m.authorErr = ""
+				m.authorEditing = false
+				m.authorInput.Blur()
+				m.setCommitAuthor(a)
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.authorInput, cmd = m.authorInput.Update(msg)
+			return m, cmd
+		case focusFiles: // For the staging panel.
+			if m.hunks != nil {
+				switch msg.String() {
+				case "up", "k":
+					m.moveHunkCursor(-1)
+				case "down", "j":
+					m.moveHunkCursor(1)
+				case "n":
+					m.moveHunk(1)
+				case "p":
+					m.moveHunk(-1)
+				case " ":
+					m.hunks.selected[m.hunks.cursor] = !m.hunks.selected[m.hunks.cursor]
+				case "h":
+					m.toggleHunk()
+				case "enter":
+					m.stageSelectedLines()
+				case "esc":
+					m.hunks = nil
+				}
+				return m, nil
+			}
+
+			switch msg.String() {
+			case "up", "k":
+				if m.fileIndex > 0 {
+					m.fileIndex--
+				}
+			case "down", "j":
+				if m.fileIndex < len(m.files)-1 {
+					m.fileIndex++
+				}
+			case " ":
+				m.toggleFile()
+			case "enter":
+				m.openHunkView()
+			case "a":
+				m.stageAllFiles()
+			}
+			return m, nil
+		case focusCommit: // Commit button selected; blocked while lint errors remain or nothing is staged.
+			if msg.String() == "enter" && m.canCommit() {
 				m.commitSelected = true
 				return m, tea.Quit
 			}
-		case 4: // Quit button selected.
+		case focusQuit: // Quit button selected.
 			if msg.String() == "enter" {
 				m.quitSelected = true
 				return m, tea.Quit
 			}
 		}
+
+	case draftTickMsg:
+		m.autosaveDraft()
+		return m, draftTick()
 	}
 
 	return m, nil
 }
 
+// setCommitError shows err above the input fields, with the tail of the output
+// of the hook that failed, if any.
+func (m *commitModel) setCommitError(err error) {
+	m.commitErr = err.Error()
+	m.commitOutput = nil
+
+	var he *hookError
+	if errors.As(err, &he) {
+		if out := strings.TrimRight(he.Output, "\n"); out != "" {
+			lines := strings.Split(out, "\n")
+			m.commitOutput = lines[max(len(lines)-maxHookOutputLines, 0):]
+		}
+	}
+}
+
+// renderFiles renders the staging panel: the changed files in the `git status --short` format,
+// with the staged column in green and the unstaged one in red.
+func (m *commitModel) renderFiles() string {
+	staged := 0
+	for _, f := range m.files {
+		if f.staged() {
+			staged++
+		}
+	}
+	s := m.renderLabel(fmt.Sprintf("Files (%d staged)", staged), focusFiles) + ":\n"
+	if m.hunks != nil {
+		return s + m.renderHunkView()
+	}
+
+	if len(m.files) == 0 {
+		s += noFocusLabelStyle.Render("  no changes") + "\n"
+	}
+	start := max(min(m.fileIndex-maxFileRows/2, len(m.files)-maxFileRows), 0)
+	end := min(start+maxFileRows, len(m.files))
+	for i := start; i < end; i++ {
+		f := m.files[i]
+		cursor := "  "
+		if m.focusIndex == focusFiles && i == m.fileIndex {
+			cursor = focusLabelStyle.Render("> ")
+		}
+		s += cursor + stagedStyle.Render(string(f.Staging)) + errorStyle.Render(string(f.Worktree)) + " " + inputStyle.Render(f.Path) + "\n"
+	}
+	if end-start < len(m.files) {
+		s += noFocusLabelStyle.Render(fmt.Sprintf("  (%d-%d of %d)", start+1, end, len(m.files))) + "\n"
+	}
+
+	if m.focusIndex == focusFiles {
+		s += noFocusLabelStyle.Render("  space: stage/unstage  enter: stage by hunk  a: stage all") + "\n"
+	}
+	if staged == 0 && !m.amend {
+		s += "  " + warningStyle.Render("nothing staged") + "\n"
+	}
+	if m.filesErr != "" {
+		s += "  " + errorStyle.Render(m.filesErr) + "\n"
+	}
+	return s
+}
+
+// renderHunkView renders the unstaged diff of the file in the hunk view, around the cursor.
+// Added lines are green, removed lines red, and the selected lines are checked.
+func (m *commitModel) renderHunkView() string {
+	v := m.hunks
+	s := "  " + inputStyle.Render(v.path) + noFocusLabelStyle.Render(fmt.Sprintf(" (%d lines selected)", countSelected(v.selected))) + "\n"
+
+	// Lay out the hunks as rows, each hunk starting with its header.
+	var rows []string
+	cursorRow := 0
+	for _, h := range v.diff.Hunks {
+		rows = append(rows, "      "+noFocusLabelStyle.Render(h.header()))
+		for i := h.Start; i < h.End; i++ {
+			l := v.diff.Lines[i]
+			text := strings.TrimRight(l.Text, "\r\n")
+			cursor, check := "  ", "    "
+			if i == v.cursor {
+				cursor = focusLabelStyle.Render("> ")
+				cursorRow = len(rows)
+			}
+			switch {
+			case l.changed() && v.selected[i]:
+				check = "[x] "
+			case l.changed():
+				check = "[ ] "
+			}
+
+			line := string(l.Op) + text
+			switch l.Op {
+			case '+':
+				line = stagedStyle.Render(line)
+			case '-':
+				line = errorStyle.Render(line)
+			default:
+				line = noFocusLabelStyle.Render(line)
+			}
+			rows = append(rows, cursor+check+line)
+		}
+	}
+
+	start := max(min(cursorRow-maxDiffRows/2, len(rows)-maxDiffRows), 0)
+	end := min(start+maxDiffRows, len(rows))
+	for _, row := range rows[start:end] {
+		s += row + "\n"
+	}
+	if end-start < len(rows) {
+		s += noFocusLabelStyle.Render(fmt.Sprintf("  (%d-%d of %d)", start+1, end, len(rows))) + "\n"
+	}
+
+	s += noFocusLabelStyle.Render("  space: select line  h: select hunk  n/p: next/previous hunk  enter: stage selected  esc: back") + "\n"
+	if m.filesErr != "" {
+		s += "  " + errorStyle.Render(m.filesErr) + "\n"
+	}
+	return s
+}
+
+// countSelected returns the number of selected lines.
+func countSelected(selected map[int]bool) int {
+	n := 0
+	for _, ok := range selected {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// renderLabel renders the label of the element at the given focus index,
+// highlighted when that element has the focus.
+func (m *commitModel) renderLabel(text string, index int) string {
+	if m.focusIndex == index {
+		return focusLabelStyle.Render(text)
+	}
+	return noFocusLabelStyle.Render(text)
+}
+
+// renderOptions renders the candidate list of an open dropdown.
+func renderOptions(options []string, selected int) string {
+	var b strings.Builder
+	for i, option := range options {
+		if i == selected {
+			b.WriteString(focusLabelStyle.Render("> " + option))
+		} else {
+			b.WriteString(noFocusLabelStyle.Render("  " + option))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
 // View returns a string that represents the current state of the model for rendering.
 func (m *commitModel) View() string {
-	// Styles for labels.
-	focusLabelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#f7b977")).Bold(true)
-	noFocusLabelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#585858"))
-	// Style for displaying input values set to white (here "#e6eae6").
-	inputStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#e6eae6"))
-
 	var s string
 
-	// Display Prefix.
-	var prefixLabel string
-	if m.focusIndex == 0 {
-		prefixLabel = focusLabelStyle.Render("Prefix")
-	} else {
-		prefixLabel = noFocusLabelStyle.Render("Prefix")
+	// Display the error of the previous commit attempt.
+	if m.commitErr != "" {
+		s += errorStyle.Render("✗ "+m.commitErr) + "\n"
+		for _, line := range m.commitOutput {
+			s += noFocusLabelStyle.Render("  "+line) + "\n"
+		}
+		s += "\n"
+	}
+
+	// Offer to restore the draft of a previous session.
+	if m.draftOffer != nil {
+		s += warningStyle.Render(fmt.Sprintf("A draft saved on %s was found: %s",
+			m.draftOffer.Time.Local().Format("2006-01-02 15:04"), m.draftOffer.Message.header())) + "\n"
+		s += noFocusLabelStyle.Render("Restore it? y: restore  n: discard") + "\n\n"
+	}
+	if m.draftErr != "" {
+		s += warningStyle.Render("! "+m.draftErr) + "\n\n"
+	}
+
+	// Display the history picker and the template switcher.
+	if m.historyOpen {
+		s += m.renderHistory() + "\n"
+	}
+	if m.templateOpen {
+		s += m.renderTemplates() + "\n"
 	}
-	s += prefixLabel + ": " + inputStyle.Render(m.prefixOptions[m.currentPrefixIndex]) + "\n"
 
-	// If the dropdown is open, display the candidate list.
-	if m.focusIndex == 0 && m.prefixDropdownOpen {
+	// Display Prefix.
+	s += m.renderLabel("Prefix", focusPrefix) + ": " + inputStyle.Render(m.prefixOptions[m.currentPrefixIndex].Name) + "\n"
+
+	// If the dropdown is open, display the candidate list with each prefix's description.
+	if m.focusIndex == focusPrefix && m.prefixDropdownOpen {
+		nameWidth := 0
+		for _, option := range m.prefixOptions {
+			nameWidth = max(nameWidth, len(option.Name))
+		}
+		options := make([]string, len(m.prefixOptions))
 		for i, option := range m.prefixOptions {
-			var line string
-			if i == m.dropdownIndex {
-				line = focusLabelStyle.Render("> " + option)
-			} else {
-				line = noFocusLabelStyle.Render("  " + option)
-			}
-			s += line + "\n"
+			options[i] = fmt.Sprintf("%-*s  %s", nameWidth, option.Name, option.Description)
 		}
+		s += renderOptions(options, m.dropdownIndex)
+	}
+	s += "\n"
+
+	// Display Scope.
+	s += m.renderLabel("Scope", focusScope) + ": " + inputStyle.Render(m.scope.View()) + "\n"
+	if m.focusIndex == focusScope && m.scopeDropdownOpen {
+		s += renderOptions(m.scopeOptions, m.scopeDropdownIndex)
 	}
 	s += "\n"
 
 	// Display Summary.
-	var summaryLabel string
-	if m.focusIndex == 1 {
-		summaryLabel = focusLabelStyle.Render("Summary")
-	} else {
-		summaryLabel = noFocusLabelStyle.Render("Summary")
+	s += m.renderLabel("Summary", focusSummary) + ": " + inputStyle.Render(m.summary.View()) + "\n\n"
+
+	// Display Description, with the applied template.
+	s += m.renderLabel("Description", focusDescription)
+	if m.template != "" {
+		s += noFocusLabelStyle.Render(" (template: " + m.template + ")")
 	}
-	s += summaryLabel + ": " + inputStyle.Render(m.summary.View()) + "\n\n"
+	s += ":\n" + inputStyle.Render(m.desc.View()) + "\n\n"
 
-	// Display Description.
-	var descriptionLabel string
-	if m.focusIndex == 2 {
-		descriptionLabel = focusLabelStyle.Render("Description")
-	} else {
-		descriptionLabel = noFocusLabelStyle.Render("Description")
+	// Display the Breaking toggle, and the BREAKING CHANGE footer while it is on.
+	toggle := "[ ]"
+	if m.breaking {
+		toggle = "[x]"
+	}
+	s += m.renderLabel("Breaking change", focusBreaking) + ": " + inputStyle.Render(toggle) + "\n\n"
+	if m.breaking {
+		s += m.renderLabel(breakingChangeToken, focusBreakingChange) + ":\n" + inputStyle.Render(m.breakingDesc.View()) + "\n\n"
+	}
+
+	// Display Trailers.
+	s += m.renderLabel("Trailers", focusTrailers) + ":\n"
+	for i, t := range m.trailers {
+		selected := m.focusIndex == focusTrailers && i == m.trailerIndex
+		switch {
+		case selected && m.trailerReplace:
+			s += "  " + m.trailerInput.View() + "\n"
+		case selected && !m.trailerEditing:
+			s += focusLabelStyle.Render("> ") + inputStyle.Render(t.String()) + "\n"
+		default:
+			s += "  " + inputStyle.Render(t.String()) + "\n"
+		}
+	}
+	if m.trailerEditing && !m.trailerReplace {
+		s += "  " + m.trailerInput.View() + "\n"
+	} else if m.focusIndex == focusTrailers && !m.trailerEditing {
+		s += noFocusLabelStyle.Render("  enter: add  e: edit  d: remove  s: sign off  up/down: select") + "\n"
+	}
+	if m.trailerErr != "" {
+		s += "  " + errorStyle.Render(m.trailerErr) + "\n"
+	}
+	s += "\n"
+
+	// Display the ticket ID, with where it goes in the message.
+	if m.focusable(focusTicket) {
+		s += m.renderLabel("Ticket", focusTicket) + ": " + inputStyle.Render(m.ticketInput.View()) +
+			noFocusLabelStyle.Render(" (inserted in the "+m.cfg.Ticket.Insert+")") + "\n\n"
+	}
+
+	// Display the commit author.
+	s += m.renderLabel("Author", focusAuthor) + ": " + inputStyle.Render(m.authorInput.View()) + "\n"
+	if m.authorErr != "" {
+		s += "  " + errorStyle.Render(m.authorErr) + "\n"
+	}
+	s += "\n"
+
+	// Display the staging panel.
+	if m.staging != nil {
+		s += m.renderFiles() + "\n"
+	}
+
+	// Display lint violations.
+	if violations := m.violations(); len(violations) > 0 {
+		for _, v := range violations {
+			if v.Severity == severityError {
+				s += errorStyle.Render("✗ "+v.String()) + "\n"
+			} else {
+				s += warningStyle.Render("! "+v.String()) + "\n"
+			}
+		}
+		s += "\n"
 	}
-	s += descriptionLabel + ":\n" + inputStyle.Render(m.desc.View()) + "\n\n"
 
 	// Display Commit and Quit buttons.
-	var commitButton, quitButton string
-	if m.focusIndex == 3 {
-		commitButton = focusLabelStyle.Render("[ Commit ]")
-	} else {
-		commitButton = noFocusLabelStyle.Render("[ Commit ]")
+	button := "[ Commit ]"
+	if m.amend {
+		button = "[ Amend ]"
 	}
-	if m.focusIndex == 4 {
-		quitButton = focusLabelStyle.Render("[ Quit ]")
+	s += m.renderLabel(button, focusCommit) + "    " + m.renderLabel("[ Quit ]", focusQuit) + "\n"
+
+	// Display the staged diff preview.
+	if m.previewOpen {
+		s += "\n" + m.renderPreview()
+	} else if m.staging != nil {
+		s += noFocusLabelStyle.Render("ctrl+o: show staged diff  ctrl+r: message history  ctrl+g: templates") + "\n"
 	} else {
-		quitButton = noFocusLabelStyle.Render("[ Quit ]")
+		s += noFocusLabelStyle.Render("ctrl+r: message history  ctrl+g: templates") + "\n"
+	}
+	return s
+}
+
+// renderTemplates renders the template switcher: "no template", then the templates with the
+// first line of their body.
+func (m *commitModel) renderTemplates() string {
+	s := focusLabelStyle.Render("Templates") + ":\n"
+
+	options := []string{"(none)"}
+	nameWidth := len(options[0])
+	for _, t := range m.templates {
+		nameWidth = max(nameWidth, len(t.Name))
+	}
+	for _, t := range m.templates {
+		first, _, _ := strings.Cut(strings.TrimSpace(t.Body), "\n")
+		options = append(options, fmt.Sprintf("%-*s  %s", nameWidth, t.Name, first))
+	}
+	s += renderOptions(options, m.templateIndex)
+	return s + noFocusLabelStyle.Render("  enter: replace the description  esc: close") + "\n"
+}
+
+// renderHistory renders the history picker: the saved messages with their date, and the
+// created commit or "draft".
+func (m *commitModel) renderHistory() string {
+	s := focusLabelStyle.Render("History") + ":\n"
+	if len(m.history) == 0 {
+		return s + noFocusLabelStyle.Render("  no saved messages") + "\n"
+	}
+
+	options := make([]string, len(m.history))
+	for i, e := range m.history {
+		options[i] = e.line()
+	}
+
+	start := max(min(m.historyIndex-maxHistoryRows/2, len(options)-maxHistoryRows), 0)
+	end := min(start+maxHistoryRows, len(options))
+	s += renderOptions(options[start:end], m.historyIndex-start)
+	if end-start < len(options) {
+		s += noFocusLabelStyle.Render(fmt.Sprintf("  (%d-%d of %d)", start+1, end, len(options))) + "\n"
+	}
+	return s + noFocusLabelStyle.Render("  enter: use this message  esc: close") + "\n"
+}
+
+// renderPreview renders the visible part of the staged diff preview, with its stats.
+func (m *commitModel) renderPreview() string {
+	s := focusLabelStyle.Render("Staged diff") + ": " + inputStyle.Render(m.previewStats.String()) + "\n"
+	if m.previewErr != "" {
+		return s + errorStyle.Render(m.previewErr) + "\n"
+	}
+	if len(m.preview) == 0 {
+		return s + noFocusLabelStyle.Render("nothing staged") + "\n"
 	}
-	s += commitButton + "    " + quitButton + "\n"
+
+	end := min(m.previewOffset+maxPreviewRows, len(m.preview))
+	for _, line := range m.preview[m.previewOffset:end] {
+		s += line + "\n"
+	}
+	s += noFocusLabelStyle.Render(fmt.Sprintf("(%d-%d of %d)  pgup/pgdown: scroll  ctrl+o: hide", m.previewOffset+1, end, len(m.preview))) + "\n"
 	return s
 }
 
-// runTUI starts the TUI and returns a CommitMessage constructed
-// from the final state of the TUI, or an error if something goes wrong.
-// If the user chooses to quit, it returns errQuit.
-func runTUI() (*commitMessage, error) {
-	m := newCommitModel()
-	p := tea.NewProgram(m)
+// message returns the commit message built from the fields, with the ticket ID inserted.
+func (m *commitModel) message() *commitMessage {
+	return withTicket(m.fields(), strings.TrimSpace(m.ticketInput.Value()), m.cfg.Ticket)
+}
+
+// fields returns the commit message as entered in the fields, without the ticket ID.
+func (m *commitModel) fields() *commitMessage {
+	return &commitMessage{
+		Prefix:         m.prefixOptions[m.currentPrefixIndex].Name,
+		Scope:          strings.TrimSpace(m.scope.Value()),
+		Breaking:       m.breaking,
+		Summary:        m.summary.Value(),
+		Description:    m.desc.Value(),
+		BreakingChange: m.breakingDesc.Value(),
+		Trailers:       slices.Clone(m.trailers),
+	}
+}
+
+// blank reports whether nothing was typed in the fields. The Description still holding the body
+// of the applied template counts as empty, so that an untouched session is not saved.
+func (m *commitModel) blank() bool {
+	msg := m.fields()
+	for _, t := range m.templates {
+		if t.Name == m.template && strings.TrimSpace(msg.Description) == strings.TrimSpace(t.Body) {
+			msg.Description = ""
+		}
+	}
+	return msg.empty()
+}
+
+// tuiResult is the outcome of the TUI, taken from its final state.
+type tuiResult struct {
+	// Message is the message as entered in the fields, nil when the TUI was quit with nothing
+	// typed. The ticket ID is inserted only in the message committed, with withTicket.
+	Message *commitMessage
+	Ticket  string
+	Author  author
+	// DraftOffered is true when the TUI was quit before the offer to restore the draft was
+	// answered, in which case the draft must be left as is.
+	DraftOffered bool
+}
+
+// result returns the outcome of the TUI. The message is returned even when quitting so that it
+// can be saved as a draft, unless nothing was typed.
+func (m *commitModel) result() tuiResult {
+	res := tuiResult{
+		Ticket:       strings.TrimSpace(m.ticketInput.Value()),
+		Author:       m.author,
+		DraftOffered: m.draftOffer != nil,
+	}
+	if !m.quitSelected || !m.blank() {
+		res.Message = m.fields()
+	}
+	return res
+}
+
+// newTUIModel returns the model of the TUI started with the options.
+func newTUIModel(cfg *settings, opts tuiOptions) *commitModel {
+	m := newCommitModel(cfg)
+	m.setAuthors(opts.Committer, opts.Authors)
+	m.setCommitAuthor(opts.Author)
+	if opts.Message != nil {
+		m.setMessage(opts.Message)
+	}
+	if opts.Err != nil {
+		m.setCommitError(opts.Err)
+	}
+	m.amend = opts.Amend
+	if opts.Staging != nil {
+		m.setStaging(opts.Staging)
+	}
+	m.history = opts.History
+	m.templates = opts.Templates
+	if opts.Message == nil {
+		m.applyTemplate(opts.Template)
+	}
+	m.ticketInput.SetValue(opts.Ticket)
+	m.drafts = opts.Drafts
+	m.draftOffer = opts.Draft
+	if opts.Message == nil {
+		m.enableSuggestions()
+	}
+	return m
+}
+
+// runTUI starts the TUI and returns its result, with the commitMessage and the author
+// constructed from the final state of the TUI, or an error if something goes wrong.
+// If the user chooses to quit, it returns errQuit along with the result.
+func runTUI(cfg *settings, opts tuiOptions) (tuiResult, error) {
+	p := tea.NewProgram(newTUIModel(cfg, opts))
 	final, err := p.Run()
 	if err != nil {
-		return nil, fmt.Errorf("error starting program: %w", err)
+		return tuiResult{}, fmt.Errorf("error starting program: %w", err)
 	}
 
 	model, ok := final.(*commitModel)
 	if !ok {
-		return nil, fmt.Errorf("type assertion failed")
+		return tuiResult{}, fmt.Errorf("type assertion failed")
 	}
 
 	if model.quitSelected {
-		return nil, errQuit
+		return model.result(), errQuit
 	}
-
-	return &commitMessage{
-		Prefix:      model.prefixOptions[model.currentPrefixIndex],
-		Summary:     model.summary.Value(),
-		Description: model.desc.Value(),
-	}, nil
+	return model.result(), nil
 }
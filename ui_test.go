@@ -1,10 +1,17 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/go-git/go-git/v5"
 )
 
 func TestUpdate_FocusMovement(t *testing.T) {
@@ -15,33 +22,33 @@ func TestUpdate_FocusMovement(t *testing.T) {
 		expectedFocus int
 	}{
 		{
-			name:          "Tab from Prefix to Summary",
-			initialFocus:  0,
+			name:          "Tab from Prefix to Scope",
+			initialFocus:  focusPrefix,
 			keyMsg:        tea.KeyMsg{Type: tea.KeyTab},
-			expectedFocus: 1,
+			expectedFocus: focusScope,
 		},
 		{
 			name:          "Tab from Summary to Description",
-			initialFocus:  1,
+			initialFocus:  focusSummary,
 			keyMsg:        tea.KeyMsg{Type: tea.KeyTab},
-			expectedFocus: 2,
+			expectedFocus: focusDescription,
 		},
 		{
 			name:          "Shift+Tab from Description to Summary",
-			initialFocus:  2,
+			initialFocus:  focusDescription,
 			keyMsg:        tea.KeyMsg{Type: tea.KeyShiftTab},
-			expectedFocus: 1,
+			expectedFocus: focusSummary,
 		},
 		{
 			name:          "Shift+Tab from Prefix to Quit (wrap-around)",
-			initialFocus:  0,
+			initialFocus:  focusPrefix,
 			keyMsg:        tea.KeyMsg{Type: tea.KeyShiftTab},
-			expectedFocus: 4,
+			expectedFocus: focusQuit,
 		},
 	}
 
 	for _, tt := range tests {
-		m := newCommitModel()
+		m := newCommitModel(defaultSettings())
 		m.focusIndex = tt.initialFocus
 		m.summaryEditing = false
 		m.descEditing = false
@@ -62,26 +69,26 @@ func TestUpdate_QuitOnQ(t *testing.T) {
 	}{
 		{
 			name:         "Quit from Summary field not editing",
-			initialFocus: 1,
+			initialFocus: focusSummary,
 			keyMsg:       tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")},
 			expectQuit:   true,
 		},
 		{
 			name:         "Quit from Description field not editing",
-			initialFocus: 2,
+			initialFocus: focusDescription,
 			keyMsg:       tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")},
 			expectQuit:   true,
 		},
 		{
 			name:         "Do not quit if Summary is being edited",
-			initialFocus: 1,
+			initialFocus: focusSummary,
 			keyMsg:       tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")},
 			expectQuit:   false,
 		},
 	}
 
 	for _, tt := range tests {
-		m := newCommitModel()
+		m := newCommitModel(defaultSettings())
 		m.focusIndex = tt.initialFocus
 		if tt.name == "Do not quit if Summary is being edited" {
 			m.summaryEditing = true
@@ -116,8 +123,8 @@ func TestUpdate_SummaryEditing(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		m := newCommitModel()
-		m.focusIndex = 1
+		m := newCommitModel(defaultSettings())
+		m.focusIndex = focusSummary
 		m.summaryEditing = false
 		_, _ = m.Update(tt.key)
 		if m.summaryEditing != tt.expectedEditing {
@@ -145,8 +152,8 @@ func TestUpdate_DescriptionEditing(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		m := newCommitModel()
-		m.focusIndex = 2
+		m := newCommitModel(defaultSettings())
+		m.focusIndex = focusDescription
 		m.descEditing = false
 		_, _ = m.Update(tt.key)
 		if m.descEditing != tt.expectedEditing {
@@ -165,14 +172,14 @@ func TestUpdate_ButtonSelection(t *testing.T) {
 	}{
 		{
 			name:         "Commit button selected",
-			initialFocus: 3,
+			initialFocus: focusCommit,
 			keyMsg:       tea.KeyMsg{Type: tea.KeyEnter},
 			expectCommit: true,
 			expectQuit:   false,
 		},
 		{
 			name:         "Quit button selected",
-			initialFocus: 4,
+			initialFocus: focusQuit,
 			keyMsg:       tea.KeyMsg{Type: tea.KeyEnter},
 			expectCommit: false,
 			expectQuit:   true,
@@ -180,8 +187,9 @@ func TestUpdate_ButtonSelection(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		m := newCommitModel()
+		m := newCommitModel(defaultSettings())
 		m.focusIndex = tt.initialFocus
+		m.summary.SetValue("add a new feature")
 		_, _ = m.Update(tt.keyMsg)
 		if m.commitSelected != tt.expectCommit {
 			t.Errorf("%s: expected commitSelected to be %v, got %v", tt.name, tt.expectCommit, m.commitSelected)
@@ -224,8 +232,8 @@ func TestUpdate_PrefixDropdown(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		m := newCommitModel()
-		m.focusIndex = 0
+		m := newCommitModel(defaultSettings())
+		m.focusIndex = focusPrefix
 		m.prefixDropdownOpen = tt.initialDropdownOpen
 		m.dropdownIndex = 0
 		_, _ = m.Update(tt.keyMsg)
@@ -239,8 +247,8 @@ func TestUpdate_PrefixDropdown(t *testing.T) {
 }
 
 func TestView_Output(t *testing.T) {
-	m := newCommitModel()
-	m.focusIndex = 1
+	m := newCommitModel(defaultSettings())
+	m.focusIndex = focusSummary
 
 	view := m.View()
 	if !strings.Contains(view, "Summary:") {
@@ -249,6 +257,9 @@ func TestView_Output(t *testing.T) {
 	if !strings.Contains(view, "Prefix:") {
 		t.Error("View output should contain 'Prefix:'")
 	}
+	if !strings.Contains(view, "Scope:") {
+		t.Error("View output should contain 'Scope:'")
+	}
 	if !strings.Contains(view, "Description:") {
 		t.Error("View output should contain 'Description:'")
 	}
@@ -259,3 +270,858 @@ func TestView_Output(t *testing.T) {
 		t.Error("View output should contain '[ Quit ]'")
 	}
 }
+
+func TestNewCommitModel_Settings(t *testing.T) {
+	cfg := &settings{
+		Prefixes: []prefixOption{
+			{Name: "feat", Description: "A new feature"},
+			{Name: "perf", Description: "A performance improvement"},
+		},
+		DefaultPrefix: "perf",
+		SummaryLimit:  50,
+	}
+
+	m := newCommitModel(cfg)
+	if m.currentPrefixIndex != 1 {
+		t.Errorf("expected currentPrefixIndex 1, got %d", m.currentPrefixIndex)
+	}
+	if m.summary.CharLimit != 50 {
+		t.Errorf("expected summary CharLimit 50, got %d", m.summary.CharLimit)
+	}
+
+	m.focusIndex = focusPrefix
+	m.prefixDropdownOpen = true
+	view := m.View()
+	if !strings.Contains(view, "A performance improvement") {
+		t.Error("View output should contain the prefix descriptions when the dropdown is open")
+	}
+}
+
+func TestUpdate_Scope(t *testing.T) {
+	tests := []struct {
+		name                 string
+		scopeOptions         []string
+		keyMsg               tea.KeyMsg
+		expectedEditing      bool
+		expectedDropdownOpen bool
+	}{
+		{
+			name:            "Enter starts editing without configured scopes",
+			keyMsg:          tea.KeyMsg{Type: tea.KeyEnter},
+			expectedEditing: true,
+		},
+		{
+			name:                 "Enter opens picker with configured scopes",
+			scopeOptions:         []string{"api", "ui"},
+			keyMsg:               tea.KeyMsg{Type: tea.KeyEnter},
+			expectedDropdownOpen: true,
+		},
+		{
+			name:            "'i' starts editing with configured scopes",
+			scopeOptions:    []string{"api", "ui"},
+			keyMsg:          tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")},
+			expectedEditing: true,
+		},
+	}
+
+	for _, tt := range tests {
+		cfg := defaultSettings()
+		cfg.Scopes = tt.scopeOptions
+		m := newCommitModel(cfg)
+		m.focusIndex = focusScope
+		_, _ = m.Update(tt.keyMsg)
+		if m.scopeEditing != tt.expectedEditing {
+			t.Errorf("%s: expected scopeEditing to be %v, got %v", tt.name, tt.expectedEditing, m.scopeEditing)
+		}
+		if m.scopeDropdownOpen != tt.expectedDropdownOpen {
+			t.Errorf("%s: expected scopeDropdownOpen to be %v, got %v", tt.name, tt.expectedDropdownOpen, m.scopeDropdownOpen)
+		}
+	}
+}
+
+func TestUpdate_ScopePickerSelection(t *testing.T) {
+	cfg := defaultSettings()
+	cfg.Scopes = []string{"api", "ui"}
+	m := newCommitModel(cfg)
+	m.focusIndex = focusScope
+
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyEnter},
+		{Type: tea.KeyDown},
+		{Type: tea.KeyEnter},
+	} {
+		_, _ = m.Update(key)
+	}
+
+	if m.scopeDropdownOpen {
+		t.Error("expected scope picker to be closed after selection")
+	}
+	if got := m.message().Scope; got != "ui" {
+		t.Errorf("expected scope %q, got %q", "ui", got)
+	}
+}
+
+func TestUpdate_BreakingToggle(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.focusIndex = focusBreaking
+
+	// The footer is skipped while the toggle is off.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.focusIndex != focusTrailers {
+		t.Errorf("expected focusIndex %d, got %d", focusTrailers, m.focusIndex)
+	}
+
+	m.focusIndex = focusBreaking
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.breaking {
+		t.Fatal("expected breaking to be toggled on")
+	}
+	if !strings.Contains(m.View(), "BREAKING CHANGE:") {
+		t.Error("View output should contain 'BREAKING CHANGE:' while breaking is on")
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if m.focusIndex != focusBreakingChange {
+		t.Errorf("expected focusIndex %d, got %d", focusBreakingChange, m.focusIndex)
+	}
+
+	m.breakingDesc.SetValue("config keys are renamed")
+	msg := m.message()
+	if !msg.Breaking || msg.BreakingChange != "config keys are renamed" {
+		t.Errorf("expected breaking message with footer, got %+v", msg)
+	}
+}
+
+func TestUpdate_Trailers(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.setAuthors(author{Name: "Tester", Email: "tester@example.com"}, []string{"Alice <alice@example.com>"})
+	m.focusIndex = focusTrailers
+
+	// Sign off with "s".
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if len(m.trailers) != 1 || m.trailers[0].String() != "Signed-off-by: Tester <tester@example.com>" {
+		t.Fatalf("expected a Signed-off-by trailer, got %+v", m.trailers)
+	}
+
+	// A second sign-off is rejected as a duplicate.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	if len(m.trailers) != 1 || m.trailerErr == "" {
+		t.Errorf("expected the duplicate trailer to be rejected, got %+v", m.trailers)
+	}
+
+	// Add a trailer through the input field.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.trailerEditing {
+		t.Fatal("expected trailerEditing to be true")
+	}
+	m.trailerInput.SetValue("Co-authored-by: Alice <alice@example.com>")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.trailers) != 2 {
+		t.Fatalf("expected 2 trailers, got %+v", m.trailers)
+	}
+	if m.trailerInput.Value() != "" {
+		t.Errorf("expected the trailer input to be cleared, got %q", m.trailerInput.Value())
+	}
+
+	// Malformed keys are rejected.
+	m.trailerInput.SetValue("Not a key: value")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.trailers) != 2 || m.trailerErr == "" {
+		t.Errorf("expected the malformed trailer to be rejected, got %+v", m.trailers)
+	}
+
+	// Leave input mode and remove the last trailer.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if len(m.trailers) != 1 {
+		t.Errorf("expected 1 trailer after removal, got %+v", m.trailers)
+	}
+
+	if got := m.message().Trailers; len(got) != 1 {
+		t.Errorf("expected the message to carry 1 trailer, got %+v", got)
+	}
+}
+
+func TestUpdate_TrailerSelection(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.setMessage(&commitMessage{Trailers: []trailer{{Key: "Refs", Value: "#1"}, {Key: "Refs", Value: "#2"}, {Key: "Refs", Value: "#3"}}})
+	m.focusIndex = focusTrailers
+	trailers := func() string {
+		var values []string
+		for _, t := range m.trailers {
+			values = append(values, t.Value)
+		}
+		return strings.Join(values, " ")
+	}
+
+	// Select the middle trailer and edit it in place.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if !strings.Contains(m.View(), "> Refs: #2") {
+		t.Error("View output should mark the selected trailer")
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if !m.trailerEditing || m.trailerInput.Value() != "Refs: #2" {
+		t.Fatalf("expected the selected trailer in the input, got %q", m.trailerInput.Value())
+	}
+	m.trailerInput.SetValue("Refs: #20")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.trailerEditing || trailers() != "#1 #20 #3" {
+		t.Errorf("expected the trailer to be replaced in place, got %q", trailers())
+	}
+
+	// A duplicate is rejected, and esc leaves the trailer unchanged.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	m.trailerInput.SetValue("Refs: #3")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.trailerErr == "" {
+		t.Error("expected the duplicate trailer to be rejected")
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.trailerEditing || trailers() != "#1 #20 #3" {
+		t.Errorf("expected the trailers to be unchanged, got %q", trailers())
+	}
+
+	// Remove the middle trailer, then the first one.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if trailers() != "#1 #3" {
+		t.Errorf("expected the selected trailer to be removed, got %q", trailers())
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if trailers() != "#3" {
+		t.Errorf("expected the first trailer to be removed, got %q", trailers())
+	}
+}
+
+func TestSetMessage(t *testing.T) {
+	msg := &commitMessage{
+		Prefix:         "fix",
+		Scope:          "api",
+		Breaking:       true,
+		Summary:        "handle timeout",
+		Description:    "Details.",
+		BreakingChange: "the timeout option is required",
+		Trailers:       []trailer{{Key: "Refs", Value: "#1"}},
+	}
+
+	m := newCommitModel(defaultSettings())
+	m.setMessage(msg)
+
+	if got := m.message().String(); got != msg.String() {
+		t.Errorf("expected %q, got %q", msg.String(), got)
+	}
+}
+
+func TestUpdate_CommitBlockedByLintErrors(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.focusIndex = focusCommit
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.commitSelected {
+		t.Error("expected commit to be blocked while the summary is empty")
+	}
+	if !strings.Contains(m.View(), "summary must not be empty") {
+		t.Error("View output should contain the lint violation")
+	}
+
+	m.summary.SetValue("Added a feature")
+	view := m.View()
+	if !strings.Contains(view, "summary-imperative") {
+		t.Error("View output should contain the summary-imperative warning")
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.commitSelected {
+		t.Error("expected warnings not to block the commit")
+	}
+}
+
+func TestView_CommitError(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	output := strings.Repeat("line\n", maxHookOutputLines) + "gofmt needed\n"
+	m.setCommitError(fmt.Errorf("%w: %w", errCommitFailed, &hookError{Hook: "pre-commit", Output: output, Err: errors.New("exit status 1")}))
+
+	if len(m.commitOutput) != maxHookOutputLines {
+		t.Errorf("expected %d output lines, got %d", maxHookOutputLines, len(m.commitOutput))
+	}
+
+	view := m.View()
+	for _, s := range []string{"pre-commit hook failed: exit status 1", "gofmt needed"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("View output should contain %q", s)
+		}
+	}
+}
+
+func TestUpdate_Author(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	original := author{Name: "Tester", Email: "tester@example.com"}
+	m.setCommitAuthor(original)
+	m.focusIndex = focusAuthor
+
+	// An invalid author is rejected and keeps the field in input mode.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.authorEditing {
+		t.Fatal("expected authorEditing to be true")
+	}
+	m.authorInput.SetValue("Alice")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.authorErr == "" || m.author != original {
+		t.Errorf("expected the invalid author to be rejected, got %+v", m.author)
+	}
+
+	// Escape discards the edit.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.authorEditing || m.authorInput.Value() != original.String() {
+		t.Errorf("expected the author input to be restored, got %q", m.authorInput.Value())
+	}
+
+	// A valid author replaces the commit author.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m.authorInput.SetValue("Alice <alice@example.com>")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	expected := author{Name: "Alice", Email: "alice@example.com"}
+	if m.authorEditing || m.author != expected {
+		t.Errorf("expected author %+v, got %+v", expected, m.author)
+	}
+	if !strings.Contains(m.View(), "Alice <alice@example.com>") {
+		t.Error("View output should contain the new author")
+	}
+}
+
+func TestUpdate_StagingPanel(t *testing.T) {
+	s, _ := newTestStagingArea(t, map[string]string{"a.txt": "a"}, map[string]string{"a.txt": "changed", "b.txt": "b"})
+	m := newCommitModel(defaultSettings())
+	m.setStaging(s)
+	m.summary.SetValue("add files")
+	m.focusIndex = focusCommit
+
+	// Nothing is staged yet, so the commit is blocked.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.commitSelected {
+		t.Error("expected commit to be blocked while nothing is staged")
+	}
+	if !strings.Contains(m.View(), "nothing staged") {
+		t.Error("View output should contain the nothing staged hint")
+	}
+
+	// Space stages the file under the cursor.
+	m.focusIndex = focusFiles
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := fileCodes(t, s); got["b.txt"] != "A " {
+		t.Errorf("expected b.txt to be staged, got %q", got["b.txt"])
+	}
+	if !strings.Contains(m.View(), "Files (1 staged)") {
+		t.Error("View output should contain the staged file count")
+	}
+
+	// Space again unstages it, and "a" stages everything.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := fileCodes(t, s); got["b.txt"] != "??" {
+		t.Errorf("expected b.txt to be unstaged, got %q", got["b.txt"])
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	expected := map[string]string{"a.txt": "M ", "b.txt": "A "}
+	if got := fileCodes(t, s); !maps.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	m.focusIndex = focusCommit
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.commitSelected {
+		t.Error("expected commit to be allowed once files are staged")
+	}
+}
+
+func TestUpdate_HunkView(t *testing.T) {
+	s, _ := newTestStagingArea(t,
+		map[string]string{"file.txt": numberedLines(20, nil)},
+		map[string]string{"file.txt": numberedLines(20, map[int]string{2: "two\n", 18: "eighteen\n"})},
+	)
+	m := newCommitModel(defaultSettings())
+	m.setStaging(s)
+	m.focusIndex = focusFiles
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.hunks == nil {
+		t.Fatal("expected the hunk view to be open")
+	}
+	view := m.View()
+	for _, s := range []string{"@@ -1,5 +1,5 @@", "@@ -15,6 +15,6 @@", "+eighteen"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("View output should contain %q", s)
+		}
+	}
+
+	// "q" does not quit while the hunk view is open.
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if cmd != nil || m.quitSelected {
+		t.Error("expected q to be ignored in the hunk view")
+	}
+
+	// Select the "+two" line only, and stage it.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if l := m.hunks.diff.Lines[m.hunks.cursor]; l.Text != "two\n" {
+		t.Fatalf("expected the cursor on +two, got %c%q", l.Op, l.Text)
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	staged, err := s.indexContent("file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := numberedLines(20, map[int]string{2: "2\ntwo\n"}); string(staged) != expected {
+		t.Errorf("expected staged content %q, got %q", expected, staged)
+	}
+
+	// The view stays open with the remaining changes; "h" then stages the second hunk.
+	if m.hunks == nil {
+		t.Fatal("expected the hunk view to stay open")
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	d, err := s.diff("file.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(d.Hunks) != 1 || d.Hunks[0].header() != "@@ -1,5 +1,4 @@" {
+		t.Errorf("expected only the removal of line 2 to remain, got %+v", d.Hunks)
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.hunks != nil {
+		t.Error("expected esc to close the hunk view")
+	}
+}
+
+func TestUpdate_StagedDiffPreview(t *testing.T) {
+	s, _ := newTestStagingArea(t, map[string]string{"a.txt": "a\n"}, map[string]string{"a.txt": "A\n", "b.txt": "b\n"})
+	m := newCommitModel(defaultSettings())
+	m.setStaging(s)
+
+	if !strings.Contains(m.View(), "ctrl+o: show staged diff") {
+		t.Error("View output should contain the preview hint")
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !m.previewOpen {
+		t.Fatal("expected the preview to be open")
+	}
+	if !strings.Contains(m.View(), "nothing staged") {
+		t.Error("View output should show that nothing is staged")
+	}
+
+	// The preview follows the staging panel.
+	m.focusIndex = focusFiles
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	view := m.View()
+	for _, s := range []string{"2 files changed, 2 insertions(+), 1 deletion(-)", "diff --git a/a.txt b/a.txt", "-a", "+A", "+b"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("View output should contain %q", s)
+		}
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if m.previewOpen || strings.Contains(m.View(), "Staged diff") {
+		t.Error("expected ctrl+o to hide the preview")
+	}
+}
+
+func TestUpdate_StagedDiffPreviewScroll(t *testing.T) {
+	s, _ := newTestStagingArea(t, nil, map[string]string{"a.txt": numberedLines(50, nil)})
+	if err := s.stage("a.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := newCommitModel(defaultSettings())
+	m.setStaging(s)
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	if m.previewOffset != maxPreviewRows/2 {
+		t.Errorf("expected offset %d, got %d", maxPreviewRows/2, m.previewOffset)
+	}
+	for range 10 {
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	}
+	if expected := len(m.preview) - maxPreviewRows; m.previewOffset != expected {
+		t.Errorf("expected offset %d at the end, got %d", expected, m.previewOffset)
+	}
+	if !strings.Contains(m.View(), "+50") {
+		t.Error("View output should contain the last line")
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgUp})
+	if expected := len(m.preview) - maxPreviewRows - maxPreviewRows/2; m.previewOffset != expected {
+		t.Errorf("expected offset %d, got %d", expected, m.previewOffset)
+	}
+}
+
+func TestUpdate_Suggestions(t *testing.T) {
+	s, _ := newTestStagingArea(t, nil, map[string]string{"README.md": "readme\n", "ui_test.go": "package main\n"})
+	m := newCommitModel(defaultSettings())
+	m.setStaging(s)
+	m.enableSuggestions()
+	m.focusIndex = focusFiles
+
+	// Staging the docs only suggests docs, and staging the tests too falls back to the default.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := m.message().Prefix; got != "docs" {
+		t.Errorf("expected prefix docs, got %q", got)
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := m.message().Prefix; got != "feat" {
+		t.Errorf("expected prefix feat, got %q", got)
+	}
+
+	// A prefix chosen by the user is kept.
+	m.currentPrefixIndex = m.cfg.prefixIndex("fix")
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := m.message().Prefix; got != "fix" {
+		t.Errorf("expected prefix fix to be kept, got %q", got)
+	}
+}
+
+func TestUpdate_ScopeSuggestion(t *testing.T) {
+	s, root := newTestStagingArea(t, nil, nil)
+	if err := os.MkdirAll(filepath.Join(root, "api"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "api", "server.go"), []byte("package api\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := s.stage("api/server.go"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := newCommitModel(defaultSettings())
+	m.setStaging(s)
+	m.enableSuggestions()
+	if got := m.scope.Value(); got != "api" {
+		t.Errorf("expected scope api, got %q", got)
+	}
+
+	// Without suggestions, e.g. when the message is given with flags, the scope is left alone.
+	m = newCommitModel(defaultSettings())
+	m.setStaging(s)
+	if got := m.scope.Value(); got != "" {
+		t.Errorf("expected no scope, got %q", got)
+	}
+}
+
+func TestUpdate_HistoryPicker(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.history = []historyEntry{
+		{Time: time.Now(), Message: &commitMessage{Prefix: "fix", Summary: "handle nil", Trailers: []trailer{{Key: "Refs", Value: "#1"}}}},
+		{Time: time.Now(), Hash: "abcdef1234", Message: &commitMessage{Prefix: "docs", Scope: "readme", Summary: "add usage"}},
+	}
+	m.summary.SetValue("typed")
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if !m.historyOpen {
+		t.Fatal("expected the history picker to be open")
+	}
+	view := m.View()
+	for _, s := range []string{"draft", "fix: handle nil", "abcdef1", "docs(readme): add usage"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("View output should contain %q", s)
+		}
+	}
+
+	// "q" does not quit, and esc closes the picker without changing the message.
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if cmd != nil || m.quitSelected {
+		t.Error("expected q to be ignored in the history picker")
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.historyOpen || m.summary.Value() != "typed" {
+		t.Errorf("expected esc to close the picker, got summary %q", m.summary.Value())
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := m.message().String(); got != "docs(readme): add usage" {
+		t.Errorf("expected the recalled message, got %q", got)
+	}
+	if m.historyOpen {
+		t.Error("expected the picker to close after recalling a message")
+	}
+}
+
+func TestUpdate_DraftOffer(t *testing.T) {
+	offer := &draft{Time: time.Now(), Message: &commitMessage{Prefix: "fix", Summary: "handle nil"}}
+
+	tests := []struct {
+		name            string
+		key             tea.KeyMsg
+		expectedSummary string
+	}{
+		{name: "Restore", key: tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}}, expectedSummary: "handle nil"},
+		{name: "Discard", key: tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}}, expectedSummary: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newCommitModel(defaultSettings())
+			m.draftOffer = offer
+			if !strings.Contains(m.View(), "fix: handle nil") {
+				t.Error("View output should contain the draft")
+			}
+
+			// Other keys, including "q", wait for an answer.
+			_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+			if cmd != nil || m.draftOffer == nil {
+				t.Fatal("expected the prompt to wait for an answer")
+			}
+
+			_, _ = m.Update(tt.key)
+			if m.draftOffer != nil {
+				t.Error("expected the prompt to be closed")
+			}
+			if got := m.summary.Value(); got != tt.expectedSummary {
+				t.Errorf("expected summary %q, got %q", tt.expectedSummary, got)
+			}
+		})
+	}
+}
+
+func TestUpdate_DraftAutosave(t *testing.T) {
+	s := &draftStore{path: filepath.Join(t.TempDir(), "main.json")}
+	m := newCommitModel(defaultSettings())
+	m.drafts = s
+	if m.Init() == nil {
+		t.Fatal("expected Init to schedule the autosave")
+	}
+
+	// An empty message is not saved.
+	_, cmd := m.Update(draftTickMsg{})
+	if cmd == nil {
+		t.Error("expected the next autosave to be scheduled")
+	}
+	if d, _ := s.load(); d != nil {
+		t.Errorf("expected no draft, got %+v", d)
+	}
+
+	m.summary.SetValue("handle nil")
+	_, _ = m.Update(draftTickMsg{})
+	d, err := s.load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d == nil || d.Message.Summary != "handle nil" {
+		t.Errorf("expected the draft to be saved, got %+v", d)
+	}
+
+	// A pending restore offer is not overwritten.
+	m.draftOffer = d
+	m.summary.SetValue("other")
+	_, _ = m.Update(draftTickMsg{})
+	if d, _ := s.load(); d == nil || d.Message.Summary != "handle nil" {
+		t.Errorf("expected the offered draft to be kept, got %+v", d)
+	}
+}
+
+func TestUpdate_DraftOfferQuit(t *testing.T) {
+	s := &draftStore{path: filepath.Join(t.TempDir(), "main.json")}
+	keepDraft(s, &commitMessage{Prefix: "fix", Summary: "handle nil"})
+	offer, err := s.load()
+	if err != nil || offer == nil {
+		t.Fatalf("expected a draft, got %+v, %v", offer, err)
+	}
+
+	m := newCommitModel(defaultSettings())
+	m.drafts = s
+	m.draftOffer = offer
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+	if cmd == nil || !m.quitSelected {
+		t.Fatal("expected Ctrl+C to quit")
+	}
+
+	res := m.result()
+	if !res.DraftOffered {
+		t.Error("expected the result to report the unanswered offer")
+	}
+	keepQuitDraft(s, res)
+	if d, _ := s.load(); d == nil || d.Message.Summary != "handle nil" {
+		t.Errorf("expected the offered draft to be kept, got %+v", d)
+	}
+}
+
+func TestTUIOptions_Retry(t *testing.T) {
+	opts := tuiOptions{Draft: &draft{Time: time.Now(), Message: &commitMessage{Prefix: "fix", Summary: "handle nil"}}}
+	if m := newTUIModel(defaultSettings(), opts); !strings.Contains(m.View(), "Restore it?") {
+		t.Fatal("View output should contain the draft prompt on the first run")
+	}
+
+	msg := &commitMessage{Prefix: "feat", Summary: "add login"}
+	opts.retry(msg, "PROJ-1", author{Name: "Alice", Email: "alice@example.com"}, errors.New("hook failed"))
+	m := newTUIModel(defaultSettings(), opts)
+	if m.draftOffer != nil || strings.Contains(m.View(), "Restore it?") {
+		t.Error("expected no draft prompt after a failed commit")
+	}
+	if got := m.message().String(); got != "feat: add login\n\nRefs: PROJ-1" {
+		t.Errorf("expected the retried message, got %q", got)
+	}
+}
+
+func TestUpdate_UntouchedTemplateQuit(t *testing.T) {
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("failed to init repo: %v", err)
+	}
+	s := &draftStore{path: filepath.Join(t.TempDir(), "main.json")}
+
+	m := newTUIModel(defaultSettings(), tuiOptions{
+		Drafts:    s,
+		Templates: []commitTemplate{{Name: "feature", Body: "## Why\n\n## How"}},
+		Template:  "feature",
+	})
+	_, _ = m.Update(draftTickMsg{})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	// The quit path of doCommit stores nothing for the bare template.
+	res := m.result()
+	if res.Message != nil {
+		t.Errorf("expected no message, got %q", res.Message.String())
+	}
+	recordMessage(repo, res.Message, "")
+	keepQuitDraft(s, res)
+	if d, _ := s.load(); d != nil {
+		t.Errorf("expected no draft, got %+v", d)
+	}
+	if entries, err := loadHistory(repo); err != nil || len(entries) != 0 {
+		t.Errorf("expected no history, got %+v, %v", entries, err)
+	}
+}
+
+func TestUpdate_TemplateSwitcher(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.templates = []commitTemplate{
+		{Name: "feature", Body: "Why:\n\nHow:"},
+		{Name: "bugfix", Body: "Cause:\n\nFix:"},
+	}
+	if !m.applyTemplate("feature") {
+		t.Fatal("expected the feature template to exist")
+	}
+	if m.desc.Value() != "Why:\n\nHow:" || !strings.Contains(m.View(), "(template: feature)") {
+		t.Errorf("expected the feature template to be applied, got %q", m.desc.Value())
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	if !m.templateOpen || m.templateIndex != 1 {
+		t.Fatalf("expected the switcher to open on the applied template, got %v %d", m.templateOpen, m.templateIndex)
+	}
+	for _, s := range []string{"(none)", "bugfix", "Cause:"} {
+		if !strings.Contains(m.View(), s) {
+			t.Errorf("View output should contain %q", s)
+		}
+	}
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.templateOpen || m.template != "bugfix" || m.desc.Value() != "Cause:\n\nFix:" {
+		t.Errorf("expected the bugfix template to be applied, got %q: %q", m.template, m.desc.Value())
+	}
+
+	// "(none)" clears the description.
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.template != "" || m.desc.Value() != "" {
+		t.Errorf("expected no template, got %q: %q", m.template, m.desc.Value())
+	}
+}
+
+func TestUpdate_Ticket(t *testing.T) {
+	m := newCommitModel(defaultSettings())
+	m.ticketInput.SetValue("PROJ-1")
+	m.summary.SetValue("add login")
+	if got := m.message().String(); got != "feat: add login\n\nRefs: PROJ-1" {
+		t.Errorf("expected the ticket trailer, got %q", got)
+	}
+	if len(m.trailers) != 0 {
+		t.Errorf("expected the trailers of the model to be unchanged, got %v", m.trailers)
+	}
+	if !strings.Contains(m.View(), "(inserted in the trailer)") {
+		t.Error("View output should contain the Ticket field")
+	}
+
+	// The ticket is edited like the other fields; clearing it leaves it out.
+	m.focusIndex = focusTicket
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.ticketEditing {
+		t.Fatal("expected ticketEditing to be true")
+	}
+	for range len("PROJ-1") {
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.ticketEditing {
+		t.Error("expected enter to leave input mode")
+	}
+	if got := m.message().String(); got != "feat: add login" {
+		t.Errorf("expected no ticket, got %q", got)
+	}
+
+	// Without insertion, the field is hidden and skipped.
+	cfg := defaultSettings()
+	cfg.Ticket.Insert = ticketInsertNone
+	m = newCommitModel(cfg)
+	if m.focusable(focusTicket) || strings.Contains(m.View(), "Ticket") {
+		t.Error("expected the Ticket field to be hidden")
+	}
+}
+
+func TestCanCommit_TicketOverSummaryLimit(t *testing.T) {
+	cfg := defaultSettings()
+	cfg.SummaryLimit = 20
+	cfg.Ticket.Insert = ticketInsertSummary
+	m := newCommitModel(cfg)
+	m.summary.SetValue("add login form")
+	if !m.canCommit() {
+		t.Fatal("expected the message within the limit to be committable")
+	}
+
+	m.ticketInput.SetValue("PROJ-1234")
+	if m.canCommit() {
+		t.Error("expected the summary with the ticket to be over the limit")
+	}
+	if !strings.Contains(m.View(), "summary is 24 characters long, limit is 20") {
+		t.Error("View output should contain the summary limit error")
+	}
+}
+
+func TestUpdate_TicketSaved(t *testing.T) {
+	s := &draftStore{path: filepath.Join(t.TempDir(), "main.json")}
+	m := newCommitModel(defaultSettings())
+	m.drafts = s
+	m.ticketInput.SetValue("PROJ-1")
+	m.summary.SetValue("add login")
+
+	// The draft and the result keep the message as typed, without the ticket.
+	_, _ = m.Update(draftTickMsg{})
+	d, err := s.load()
+	if err != nil || d == nil {
+		t.Fatalf("expected a draft, got %+v, %v", d, err)
+	}
+	if got := d.Message.String(); got != "feat: add login" {
+		t.Errorf("expected the draft without the ticket, got %q", got)
+	}
+	res := m.result()
+	if got := res.Message.String(); got != "feat: add login" || res.Ticket != "PROJ-1" {
+		t.Errorf("expected the message without the ticket and PROJ-1, got %q and %q", got, res.Ticket)
+	}
+
+	// A restored message takes the edited ticket only.
+	m = newCommitModel(defaultSettings())
+	m.setMessage(d.Message)
+	m.ticketInput.SetValue("PROJ-2")
+	if got := m.message().String(); got != "feat: add login\n\nRefs: PROJ-2" {
+		t.Errorf("expected the new ticket only, got %q", got)
+	}
+}